@@ -2,40 +2,41 @@ package check
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/foomo/petze/config"
 )
 
-func checkExpectStringEquals(expect config.Expect, expected, actual string) (ok bool, info string) {
-	ok = expected == actual
-	if !ok {
-		info = "actual: \"" + actual + "\" != expected: \"" + expected + "\""
-		return
+// checkMinMaxCount checks all configured count constraints, all of them have to be met
+func checkMinMaxCount(expect config.Expect, length int64) (ok bool, info string) {
+	if !expect.HasRange() {
+		panic("this is a programming error - check your usage of minMaxCount")
+	}
+	infos := []string{}
+	if expect.Min != nil && length < *expect.Min {
+		infos = append(infos, fmt.Sprint("min actual: ", length, " < expected: ", *expect.Min))
+	}
+	if expect.Max != nil && length > *expect.Max {
+		infos = append(infos, fmt.Sprint("max actual: ", length, " > expected: ", *expect.Max))
+	}
+	if expect.Count != nil && length != *expect.Count {
+		infos = append(infos, fmt.Sprint("count actual: ", length, " != expected: ", *expect.Count))
+	}
+	if expect.Between != nil {
+		switch {
+		case len(expect.Between) != 2:
+			infos = append(infos, fmt.Sprint("between needs exactly two values [min, max], got: ", expect.Between))
+		case length < expect.Between[0] || length > expect.Between[1]:
+			infos = append(infos, fmt.Sprint("between actual: ", length, " not in expected: [", expect.Between[0], ", ", expect.Between[1], "]"))
+		}
 	}
-	return
+	return checkAll(infos)
 }
 
-func checkMinMaxCount(expect config.Expect, length int64) (ok bool, info string) {
-	switch true {
-	case expect.Min != nil:
-		ok = length >= *expect.Min
-		if !ok {
-			info = fmt.Sprint("min actual: ", length, " < expected: ", *expect.Min)
-		}
-		return
-	case expect.Max != nil:
-		ok = length <= *expect.Max
-		if !ok {
-			info = fmt.Sprint("max actual: ", length, " > expected: ", *expect.Max)
-		}
-		return
-	case expect.Count != nil:
-		ok = length == *expect.Count
-		if !ok {
-			info = fmt.Sprint("count actual: ", length, " != expected: ", *expect.Count)
-		}
-		return
-	default:
-		panic("this is a programming error - check your usage of minMaxCount")
+// checkAll is ok, if there are no infos, otherwise it joins them
+func checkAll(infos []string) (ok bool, info string) {
+	if len(infos) == 0 {
+		return true, ""
 	}
+	return false, strings.Join(infos, ", ")
 }
@@ -38,3 +38,28 @@ func TestCheckMinMaxPanic(t *testing.T) {
 
 	checkMinMaxCount(config.Expect{Equals: ""}, 3)
 }
+
+var checkRangeTestCases = []struct {
+	expect  config.Expect
+	length  int64
+	ok      bool
+	message string
+}{
+	{expect: config.Expect{Min: &[]int64{1}[0], Max: &[]int64{10}[0]}, length: 5, ok: true, message: "min and max within"},
+	{expect: config.Expect{Min: &[]int64{1}[0], Max: &[]int64{10}[0]}, length: 11, ok: false, message: "min and max above max"},
+	{expect: config.Expect{Min: &[]int64{1}[0], Max: &[]int64{10}[0]}, length: 0, ok: false, message: "min and max below min"},
+	{expect: config.Expect{Between: []int64{10, 50}}, length: 10, ok: true, message: "between lower bound"},
+	{expect: config.Expect{Between: []int64{10, 50}}, length: 50, ok: true, message: "between upper bound"},
+	{expect: config.Expect{Between: []int64{10, 50}}, length: 51, ok: false, message: "between above"},
+	{expect: config.Expect{Between: []int64{10}}, length: 10, ok: false, message: "between with one value"},
+	{expect: config.Expect{Min: &[]int64{1}[0], Count: &[]int64{3}[0]}, length: 2, ok: false, message: "min ok, count not"},
+}
+
+func TestCheckMinMaxCountCombined(t *testing.T) {
+	for _, test := range checkRangeTestCases {
+		ok, info := checkMinMaxCount(test.expect, test.length)
+		if ok != test.ok {
+			t.Error(test.message, info)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/foomo/petze/config"
+)
+
+// selection is what a selector extracted from a response
+type selection struct {
+	// number of matches - used for exists and absent
+	matches int64
+	// length checked by min, max, count and between
+	length int64
+	// values checked by the value operators
+	values []interface{}
+	// if any is set, one value passing the value operators is sufficient, otherwise all have to pass
+	any bool
+}
+
+// evaluate checks a selection against all constraints of an expectation
+func evaluate(expect config.Expect, sel selection) (ok bool, info string) {
+	if !expect.HasRange() && !expect.HasValueConstraint() && !expect.Exists && !expect.Absent {
+		return false, "no expectation configured"
+	}
+	infos := []string{}
+	if expect.Absent && sel.matches > 0 {
+		infos = append(infos, fmt.Sprint("expected absent, but found ", sel.matches, " match(es)"))
+	}
+	if expect.Exists && sel.matches == 0 {
+		infos = append(infos, "expected to exist, but nothing was found")
+	}
+	if expect.HasRange() {
+		if ok, info := checkMinMaxCount(expect, sel.length); !ok {
+			infos = append(infos, info)
+		}
+	}
+	if expect.HasValueConstraint() {
+		values, any := sel.values, sel.any
+		switch expect.Elements {
+		case "":
+		case config.ElementsAll:
+			any = false
+		case config.ElementsAny:
+			any = true
+		case config.ElementsFirst:
+			if len(values) > 1 {
+				values = values[:1]
+			}
+		default:
+			infos = append(infos, "unknown elements mode: \""+expect.Elements+"\", use one of all, any or first")
+		}
+		if ok, info := checkValues(expect, values, any); !ok {
+			infos = append(infos, info)
+		}
+	}
+	return checkAll(infos)
+}
+
+// checkValues applies the value operators to all values
+func checkValues(expect config.Expect, values []interface{}, any bool) (ok bool, info string) {
+	if len(values) == 0 {
+		return false, "no value to compare"
+	}
+	for i, value := range values {
+		valueOK, valueInfo := checkValue(expect, value)
+		switch {
+		case any && valueOK:
+			return true, ""
+		case !any && !valueOK:
+			if len(values) > 1 {
+				valueInfo = fmt.Sprint("value[", i, "]: ", valueInfo)
+			}
+			return false, valueInfo
+		case any && i == 0:
+			info = valueInfo
+		}
+	}
+	if any {
+		return false, fmt.Sprint("none of ", len(values), " values matched, first: ", info)
+	}
+	return true, ""
+}
+
+// checkValue applies the value operators to a single value
+func checkValue(expect config.Expect, actual interface{}) (ok bool, info string) {
+	infos := []string{}
+	if expect.HasEquals() && !equal(expect.Equals, actual) {
+		infos = append(infos, "actual: "+formatValue(actual)+" != expected: "+formatValue(expect.Equals))
+	}
+	if expect.HasNotEquals() && equal(expect.NotEquals, actual) {
+		infos = append(infos, "actual: "+formatValue(actual)+" == not expected: "+formatValue(expect.NotEquals))
+	}
+	if expect.Contains != "" && !contains(actual, expect.Contains) {
+		infos = append(infos, "actual: "+formatValue(actual)+" does not contain: \""+expect.Contains+"\"")
+	}
+	if expect.Matches != "" {
+		regex, errCompile := regexp.Compile(expect.Matches)
+		switch {
+		case errCompile != nil:
+			infos = append(infos, "could not compile regex '"+expect.Matches+"'")
+		case !regex.MatchString(toString(actual)):
+			infos = append(infos, "actual: "+formatValue(actual)+" does not match: '"+expect.Matches+"'")
+		}
+	}
+	if expect.OneOf != nil {
+		found := false
+		for _, candidate := range expect.OneOf {
+			if equal(candidate, actual) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			infos = append(infos, "actual: "+formatValue(actual)+" is not one of: "+formatValue(expect.OneOf))
+		}
+	}
+	if expect.Gt != nil || expect.Gte != nil || expect.Lt != nil || expect.Lte != nil {
+		number, isNumber := toFloat(actual)
+		if !isNumber {
+			infos = append(infos, "actual: "+formatValue(actual)+" is not a number")
+		} else {
+			if expect.Gt != nil && !(number > *expect.Gt) {
+				infos = append(infos, fmt.Sprint("actual: ", number, " is not > ", *expect.Gt))
+			}
+			if expect.Gte != nil && !(number >= *expect.Gte) {
+				infos = append(infos, fmt.Sprint("actual: ", number, " is not >= ", *expect.Gte))
+			}
+			if expect.Lt != nil && !(number < *expect.Lt) {
+				infos = append(infos, fmt.Sprint("actual: ", number, " is not < ", *expect.Lt))
+			}
+			if expect.Lte != nil && !(number <= *expect.Lte) {
+				infos = append(infos, fmt.Sprint("actual: ", number, " is not <= ", *expect.Lte))
+			}
+		}
+	}
+	return checkAll(infos)
+}
+
+// equal compares an expected value from the config with an extracted value
+// strings extracted from html or regex results are converted to the type of the expected value
+func equal(expected, actual interface{}) bool {
+	switch e := expected.(type) {
+	case nil:
+		return actual == nil
+	case bool:
+		switch a := actual.(type) {
+		case bool:
+			return a == e
+		case string:
+			b, errParse := strconv.ParseBool(strings.TrimSpace(a))
+			return errParse == nil && b == e
+		}
+		return false
+	case string:
+		switch a := actual.(type) {
+		case string:
+			return a == e
+		case nil:
+			// null is only matched by an explicit equals: null
+			return false
+		}
+		// allows to compare to formatted numbers
+		return formatValue(actual) == e
+	}
+	if e, ok := toFloat(expected); ok {
+		a, ok := toFloat(actual)
+		return ok && a == e
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+func contains(actual interface{}, substring string) bool {
+	if elements, ok := actual.([]interface{}); ok {
+		for _, element := range elements {
+			if equal(substring, element) {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.Contains(toString(actual), substring)
+}
+
+// toFloat converts numbers and numeric strings
+func toFloat(value interface{}) (number float64, ok bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, errParse := v.Float64()
+		return f, errParse == nil
+	case string:
+		f, errParse := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, errParse == nil
+	}
+	return 0, false
+}
+
+// toString returns strings as they are and everything else formatted
+func toString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return formatValue(value)
+}
+
+// formatValue formats a value for info messages - as JSON if possible
+func formatValue(value interface{}) string {
+	jsonBytes, errJSON := json.Marshal(value)
+	if errJSON != nil {
+		return fmt.Sprint(value)
+	}
+	return string(jsonBytes)
+}
@@ -0,0 +1,66 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/foomo/petze/config"
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+const (
+	operatorTestJSON = `{"status": true, "stock": 3, "name": "shoe", "tags": ["red", "blue"]}`
+	operatorTestHTML = `<html><body><h1>Shoe</h1><span class="stock">3</span><span class="status">true</span></body></html>`
+	operatorTestText = `name=shoe stock=3 status=true`
+)
+
+var operatorTestCases = []struct {
+	jsonPath string
+	goQuery  string
+	regex    string
+	expect   config.Expect
+	ok       bool
+	message  string
+}{
+	{`$.status+`, `.status`, `true`, config.Expect{Equals: true}, true, "bool equals"},
+	{`$.status+`, `.status`, `true`, config.Expect{Equals: false}, false, "bool equals mismatch"},
+	{`$.stock+`, `.stock`, `\d+`, config.Expect{Equals: 3}, true, "number equals"},
+	{`$.stock+`, `.stock`, `\d+`, config.Expect{NotEquals: 3}, false, "number not equals"},
+	{`$.name+`, `h1`, `shoe`, config.Expect{NotEquals: "boot"}, true, "string not equals"},
+	{`$.stock+`, `.stock`, `\d+`, config.Expect{Gt: float(0)}, true, "gt"},
+	{`$.stock+`, `.stock`, `\d+`, config.Expect{Gte: float(3), Lte: float(3)}, true, "gte and lte"},
+	{`$.stock+`, `.stock`, `\d+`, config.Expect{Lt: float(3)}, false, "lt"},
+	{`$.name+`, `h1`, `shoe`, config.Expect{Gt: float(0)}, false, "gt on a string"},
+	{`$.name+`, `h1`, `[Ss]hoe`, config.Expect{Matches: `^[Ss]ho`}, true, "matches"},
+	{`$.name+`, `h1`, `[Ss]hoe`, config.Expect{Matches: `^boot`}, false, "matches mismatch"},
+	{`$.stock+`, `.stock`, `\d+`, config.Expect{OneOf: []interface{}{1, 2, 3}}, true, "one of"},
+	{`$.stock+`, `.stock`, `\d+`, config.Expect{OneOf: []interface{}{1, 2}}, false, "one of mismatch"},
+	{`$.name+`, `h1`, `shoe`, config.Expect{Exists: true}, true, "exists"},
+	{`$.nope+`, `.nope`, `nope`, config.Expect{Exists: true}, false, "exists mismatch"},
+	{`$.nope+`, `.nope`, `nope`, config.Expect{Absent: true}, true, "absent"},
+	{`$.name+`, `h1`, `shoe`, config.Expect{Absent: true}, false, "absent mismatch"},
+	{`$.stock+`, `.stock`, `\d+`, config.Expect{Exists: true, Equals: "3", Lt: float(10)}, true, "combined"},
+	{`$.stock+`, `.stock`, `\d+`, config.Expect{Exists: true, Equals: "3", Lt: float(2)}, false, "combined mismatch"},
+}
+
+func TestOperators(t *testing.T) {
+	doc, errDoc := goquery.NewDocumentFromReader(strings.NewReader(operatorTestHTML))
+	if errDoc != nil {
+		t.Fatal(errDoc)
+	}
+	for _, test := range operatorTestCases {
+		if ok, info := JSONPath([]byte(operatorTestJSON), test.jsonPath, test.expect); ok != test.ok {
+			t.Error("jsonPath:", test.message, info)
+		}
+		if ok, info := Goquery(doc, test.goQuery, test.expect); ok != test.ok {
+			t.Error("goQuery:", test.message, info)
+		}
+		if ok, info := Regex([]byte(operatorTestText), test.regex, test.expect); ok != test.ok {
+			t.Error("regex:", test.message, info)
+		}
+	}
+}
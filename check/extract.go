@@ -0,0 +1,53 @@
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ExtractJSONPath returns the first result of a json path - strings are unquoted, other values are returned as raw JSON
+func ExtractJSONPath(jsonBytes []byte, selector string) (value string, err error) {
+	raw, errSelect := selectJSON(jsonBytes, selector)
+	if errSelect != nil {
+		return "", errSelect
+	}
+	var str string
+	if json.Unmarshal(raw, &str) == nil {
+		return str, nil
+	}
+	return string(raw), nil
+}
+
+// ExtractGoQuery returns the value of the first element matching a css selector with an optional @attribute, @html() or @text() suffix
+func ExtractGoQuery(doc *goquery.Document, selector string) (value string, err error) {
+	cssSelector, extract := splitGoquerySelector(selector)
+	found := false
+	doc.Find(cssSelector).EachWithBreak(func(i int, element *goquery.Selection) bool {
+		value, found = extractValue(element, extract)
+		return !found
+	})
+	if !found {
+		return "", errors.New("no result for " + selector)
+	}
+	return value, nil
+}
+
+// ExtractRegex returns the first capturing group of the first match or the whole match, if there is no group
+func ExtractRegex(data []byte, selector string) (value string, err error) {
+	regex, errCompile := regexp.Compile(selector)
+	if errCompile != nil {
+		return "", errors.New("could not compile regex '" + selector + "'")
+	}
+	match := regex.FindSubmatch(data)
+	switch {
+	case match == nil:
+		return "", errors.New("no match for " + selector)
+	case len(match) > 1:
+		return string(match[1]), nil
+	default:
+		return string(match[0]), nil
+	}
+}
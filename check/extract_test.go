@@ -0,0 +1,58 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestExtractJSONPath(t *testing.T) {
+	data := []byte(`{"token": "abc", "order": {"id": 42, "tags": ["a", "b"]}}`)
+	for selector, expected := range map[string]string{
+		"$.token+":      "abc",
+		"$.order.id+":   "42",
+		"$.order.tags+": `["a","b"]`,
+	} {
+		value, err := ExtractJSONPath(data, selector)
+		if err != nil || value != expected {
+			t.Error("unexpected value for", selector, value, err)
+		}
+	}
+	if _, err := ExtractJSONPath(data, "$.missing+"); err == nil {
+		t.Error("expected an error for a missing value")
+	}
+}
+
+func TestExtractGoQuery(t *testing.T) {
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(`<form><input name="csrf" value="t0k3n"><h1>one</h1><h1>two</h1></form>`))
+	for selector, expected := range map[string]string{
+		"input[name=csrf]@value": "t0k3n",
+		"h1":                     "one",
+		"form@html()":            `<input name="csrf" value="t0k3n"/><h1>one</h1><h1>two</h1>`,
+	} {
+		value, err := ExtractGoQuery(doc, selector)
+		if err != nil || value != expected {
+			t.Error("unexpected value for", selector, value, err)
+		}
+	}
+	if _, err := ExtractGoQuery(doc, "input@placeholder"); err == nil {
+		t.Error("expected an error for a missing attribute")
+	}
+}
+
+func TestExtractRegex(t *testing.T) {
+	data := []byte(`session=abc123; order=42`)
+	for selector, expected := range map[string]string{
+		`order=\d+`:           "order=42",
+		`session=([a-z0-9]+)`: "abc123",
+	} {
+		value, err := ExtractRegex(data, selector)
+		if err != nil || value != expected {
+			t.Error("unexpected value for", selector, value, err)
+		}
+	}
+	if _, err := ExtractRegex(data, "missing"); err == nil {
+		t.Error("expected an error for no match")
+	}
+}
@@ -1,31 +1,80 @@
 package check
 
 import (
-	"reflect"
+	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/foomo/petze/config"
 )
 
+const (
+	// selector suffixes to extract the inner html or the text of each element instead of an attribute
+	extractHTML = "html()"
+	extractText = "text()"
+)
+
+// Goquery checks a css selector - an optional suffix @attribute, @html() or @text() selects the value of each element
+// e.g. meta[name=robots]@content
 func Goquery(doc *goquery.Document, selector string, expect config.Expect) (ok bool, info string) {
-	switch true {
-	case expect.Max != nil, expect.Min != nil, expect.Count != nil:
-		return checkMinMaxCount(expect, int64(doc.Find(selector).Length()))
-	case expect.Contains != "":
-		info = "contains is not implemented"
-	case expect.Equals != nil:
-		info = "equals is not implemented"
-		expectRefl := reflect.ValueOf(expect.Equals)
-		switch expectRefl.Kind().String() {
-		case "string":
-			//res := doc.Find(selector)
-			//fmt.Println(selector, "length", res.Length(), "text", res.Text())
-			actualString := doc.Find(selector).Text()
-			expectString := expect.Equals.(string)
-			return checkExpectStringEquals(expect, expectString, actualString)
-		default:
-			info += " for kind " + expectRefl.Kind().String()
+	cssSelector, extract := splitGoquerySelector(selector)
+	res := doc.Find(cssSelector)
+	sel := selection{}
+	if extract == "" && expect.Elements == "" {
+		sel.matches = int64(res.Length())
+		sel.length = int64(res.Length())
+		if res.Length() > 0 {
+			// compare the text of all matches
+			sel.values = []interface{}{res.Text()}
+		}
+		return evaluate(expect, sel)
+	}
+	res.Each(func(i int, element *goquery.Selection) {
+		if value, exists := extractValue(element, extract); exists {
+			sel.values = append(sel.values, value)
 		}
+	})
+	sel.matches = int64(len(sel.values))
+	sel.length = int64(len(sel.values))
+	return evaluate(expect, sel)
+}
+
+func extractValue(element *goquery.Selection, extract string) (value string, exists bool) {
+	switch extract {
+	case "", extractText:
+		return element.Text(), true
+	case extractHTML:
+		html, errHTML := element.Html()
+		return html, errHTML == nil
+	default:
+		return element.Attr(extract)
+	}
+}
+
+// splitGoquerySelector splits at the last @, that is not part of an attribute selector or a quoted string
+func splitGoquerySelector(selector string) (cssSelector, extract string) {
+	var (
+		depth int
+		quote rune
+		split = -1
+	)
+	for i, r := range selector {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case r == '@' && depth == 0:
+			split = i
+		}
+	}
+	if split < 0 {
+		return selector, ""
 	}
-	return
+	return strings.TrimSpace(selector[:split]), strings.TrimSpace(selector[split+1:])
 }
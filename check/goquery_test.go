@@ -0,0 +1,58 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/foomo/petze/config"
+)
+
+const seoTestHTML = `<html><head>
+<meta name="robots" content="index, follow">
+<link rel="canonical" href="https://www.example.com/shoes">
+<link rel="alternate" hreflang="de" href="https://www.example.com/de/shoes">
+<link rel="alternate" hreflang="fr" href="https://www.example.com/fr/shoes">
+<link rel="alternate" hreflang="x-default" href="https://www.example.com/shoes">
+</head><body>
+<ul><li>one</li><li>two</li><li>three</li></ul>
+<a href="mailto:shop@example.com">mail</a>
+<div class="teaser"><b>bold</b> text</div>
+</body></html>`
+
+var goqueryTestCases = []struct {
+	selector string
+	expect   config.Expect
+	ok       bool
+	message  string
+}{
+	{`meta[name=robots]@content`, config.Expect{Contains: "index"}, true, "attribute contains"},
+	{`meta[name=robots]@content`, config.Expect{Contains: "noindex"}, false, "attribute does not contain"},
+	{`link[rel=canonical]@href`, config.Expect{Equals: "https://www.example.com/shoes"}, true, "canonical"},
+	{`link[rel=alternate]@hreflang`, config.Expect{OneOf: []interface{}{"de", "fr", "x-default"}}, true, "hreflang all"},
+	{`link[rel=alternate]@hreflang`, config.Expect{Equals: "x-default", Elements: config.ElementsAny}, true, "hreflang any"},
+	{`link[rel=alternate]@hreflang`, config.Expect{Equals: "x-default"}, false, "hreflang all equal"},
+	{`link[rel=alternate]@hreflang`, config.Expect{Equals: "de", Elements: config.ElementsFirst}, true, "hreflang first"},
+	{`link[rel=alternate]@hreflang`, config.Expect{Count: &[]int64{3}[0]}, true, "attribute count"},
+	{`link@hreflang`, config.Expect{Count: &[]int64{3}[0]}, true, "elements without the attribute are not counted"},
+	{`link[rel=alternate]@href`, config.Expect{Matches: `^https://www\.example\.com/`}, true, "matches"},
+	{`a[href="mailto:shop@example.com"]`, config.Expect{Count: &[]int64{1}[0]}, true, "@ in an attribute selector"},
+	{`a[href="mailto:shop@example.com"]@href`, config.Expect{Equals: "mailto:shop@example.com"}, true, "@ in an attribute selector with an attribute"},
+	{`.teaser@html()`, config.Expect{Contains: "<b>bold</b>"}, true, "inner html"},
+	{`ul li`, config.Expect{Equals: "onetwothree"}, true, "concatenated text"},
+	{`ul li@text()`, config.Expect{Equals: "two", Elements: config.ElementsAny}, true, "text per element"},
+	{`ul li`, config.Expect{Matches: `^[a-z]+$`, Elements: config.ElementsAll}, true, "text per element with elements mode"},
+	{`ul li`, config.Expect{Equals: "one", Elements: "some"}, false, "unknown elements mode"},
+}
+
+func TestGoquery(t *testing.T) {
+	doc, errDoc := goquery.NewDocumentFromReader(strings.NewReader(seoTestHTML))
+	if errDoc != nil {
+		t.Fatal(errDoc)
+	}
+	for _, test := range goqueryTestCases {
+		if ok, info := Goquery(doc, test.selector, test.expect); ok != test.ok {
+			t.Error(test.message, info)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package check
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/foomo/petze/config"
+)
+
+const headerContentType = "Content-Type"
+
+// Header checks all values of a response header - unless elements is set, every value has to pass the value operators
+func Header(header http.Header, name string, expect config.Expect) (ok bool, info string) {
+	values := header[http.CanonicalHeaderKey(name)]
+	sel := selection{
+		matches: int64(len(values)),
+		length:  int64(len(values)),
+		values:  make([]interface{}, len(values)),
+	}
+	expected, compareMediaType := expect.Equals.(string)
+	compareMediaType = compareMediaType && http.CanonicalHeaderKey(name) == headerContentType
+	for i, value := range values {
+		// text/html is equal to text/html; charset=utf-8
+		if compareMediaType && MediaTypeMatches(value, expected) {
+			value = expected
+		}
+		sel.values[i] = value
+	}
+	return evaluate(expect, sel)
+}
+
+// MediaTypeMatches compares media types case insensitive, parameters are only compared if they are expected
+func MediaTypeMatches(actual, expected string) bool {
+	actualType, actualParams, errActual := mime.ParseMediaType(actual)
+	expectedType, expectedParams, errExpected := mime.ParseMediaType(expected)
+	if errActual != nil || errExpected != nil {
+		return actual == expected
+	}
+	if actualType != expectedType {
+		return false
+	}
+	for name, value := range expectedParams {
+		if !strings.EqualFold(actualParams[name], value) {
+			return false
+		}
+	}
+	return true
+}
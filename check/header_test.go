@@ -0,0 +1,51 @@
+package check
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/foomo/petze/config"
+)
+
+func TestHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	header.Add("Set-Cookie", "session=abc; Secure; HttpOnly")
+	header.Add("Set-Cookie", "tracking=xyz")
+	header.Set("X-Request-Id", "req-42")
+
+	tests := []struct {
+		name   string
+		expect config.Expect
+		ok     bool
+	}{
+		{name: "content-type", expect: expectEquals("text/html"), ok: true},
+		{name: "Content-Type", expect: expectEquals("text/html; charset=UTF-8"), ok: true},
+		{name: "Content-Type", expect: expectEquals("text/html; charset=iso-8859-1"), ok: false},
+		{name: "Content-Type", expect: expectEquals("application/json"), ok: false},
+		{name: "X-Request-Id", expect: config.Expect{Matches: `^req-\d+$`}, ok: true},
+		{name: "X-Request-Id", expect: config.Expect{Exists: true}, ok: true},
+		{name: "Server", expect: config.Expect{Absent: true}, ok: true},
+		{name: "Server", expect: config.Expect{Exists: true}, ok: false},
+		{name: "Server", expect: expectEquals("nginx"), ok: false},
+		// all values of a multi-valued header have to pass by default
+		{name: "Set-Cookie", expect: expectContains("="), ok: true},
+		{name: "Set-Cookie", expect: expectContains("Secure"), ok: false},
+		{name: "Set-Cookie", expect: config.Expect{Contains: "Secure", Elements: config.ElementsAny}, ok: true},
+		{name: "Set-Cookie", expect: expectCount(2), ok: true},
+	}
+	for _, test := range tests {
+		if ok, info := Header(header, test.name, test.expect); ok != test.ok {
+			t.Error(test.name, test.expect, "expected", test.ok, "got", ok, info)
+		}
+	}
+}
+
+func TestMediaTypeMatches(t *testing.T) {
+	if !MediaTypeMatches("Application/JSON; charset=utf-8", "application/json") {
+		t.Error("media types have to be compared case insensitive without parameters")
+	}
+	if MediaTypeMatches("application/json", "application/json; charset=utf-8") {
+		t.Error("expected parameters have to be present")
+	}
+}
@@ -1,18 +1,16 @@
 package check
 
 import (
-	"reflect"
+	"bytes"
+	"encoding/json"
+	"strings"
 
 	"github.com/Jeffail/gabs"
 	"github.com/JumboInteractiveLimited/jsonpath"
 	"github.com/foomo/petze/config"
-
-	log "github.com/sirupsen/logrus"
 )
 
 func JSONPath(jsonBytes []byte, selector string, expect config.Expect) (ok bool, info string) {
-	info = "check not implemented"
-
 	paths, errParsePaths := jsonpath.ParsePaths(selector)
 	if errParsePaths != nil {
 		info = "could not parse json paths : " + errParsePaths.Error()
@@ -25,64 +23,72 @@ func JSONPath(jsonBytes []byte, selector string, expect config.Expect) (ok bool,
 		return
 	}
 
-	result, evalOK := eval.Next()
-	if !evalOK {
-		info = "could not eval jsonpath: " + selector
-		return
+	sel := selection{}
+	raw := []string{}
+	for {
+		result, evalOK := eval.Next()
+		if !evalOK {
+			break
+		}
+		if len(result.Value) == 0 {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(result.Value))
+		decoder.UseNumber()
+		container, jsonErr := gabs.ParseJSONDecoder(decoder)
+		if jsonErr != nil {
+			info = "could not parse json: " + jsonErr.Error() + " " + string(result.Value)
+			return
+		}
+		sel.values = append(sel.values, container.Data())
+		raw = append(raw, string(result.Value))
 	}
 
-	if len(result.Value) == 0 {
-		info = "no result for " + selector
+	if eval.Error != nil {
+		info = "could not evaluate json path " + eval.Error.Error()
 		return
 	}
 
-	json, jsonErr := gabs.ParseJSON(result.Value)
-	if jsonErr != nil {
-		info = "could not parse json: " + jsonErr.Error() + " " + string(result.Value)
-		return
+	sel.matches = int64(len(sel.values))
+	switch len(sel.values) {
+	case 0:
+		if !expect.Absent {
+			info = "no result for " + selector
+			return
+		}
+	case 1:
+		sel.length = jsonLength(sel.values[0])
+	default:
+		// a selector with wildcards counts its results
+		sel.length = int64(len(sel.values))
 	}
 
-	//fmt.Println(json.Data()) // true -> show keys in pretty string
-	data := json.Data()
-	refl := reflect.ValueOf(data)
-	length := int64(-1)
-	resultIsString := false
-	resultString := ""
-	switch refl.Kind().String() {
-	case "string":
-		resultIsString = true
-		resultString = data.(string)
-	case "slice":
-		length = int64(len(data.([]interface{})))
-	default:
-		log.Warn("wtf would that be", refl.Type().String(), refl.Kind().String())
+	ok, info = evaluate(expect, sel)
+	if !ok && len(raw) > 0 {
+		info = selector + ": " + info + ", actual json: " + truncate(strings.Join(raw, ", "), maxJSONDisplayLength)
 	}
+	return
+}
 
-	if eval.Error != nil {
-		info = "could not evaluate json path " + eval.Error.Error()
-		return
+const maxJSONDisplayLength = 200
+
+// jsonLength counts the elements of an array, the keys of an object, null as empty and scalars as one
+func jsonLength(value interface{}) int64 {
+	switch v := value.(type) {
+	case []interface{}:
+		return int64(len(v))
+	case map[string]interface{}:
+		return int64(len(v))
+	case nil:
+		return 0
+	default:
+		return 1
 	}
+}
 
-	switch true {
-	case expect.Min != nil, expect.Max != nil, expect.Count != nil:
-		return checkMinMaxCount(expect, int64(length))
-	case expect.Equals != nil:
-		expected := ""
-		if reflect.ValueOf(expect.Equals).Type().String() != "string" {
-			info = "jsonpath can only compare to string"
-			return
-		}
-		expected = expect.Equals.(string)
-		if !resultIsString {
-			info = "result is not a string"
-			return
-		}
-		if resultString == expected {
-			ok = true
-			return
-		}
-		info = "actual: " + resultString + " != expected: " + expected
-		return
+func truncate(s string, maxLength int) string {
+	if len(s) > maxLength {
+		return s[:maxLength] + "..."
 	}
-	return
+	return s
 }
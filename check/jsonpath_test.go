@@ -0,0 +1,47 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/foomo/petze/config"
+)
+
+const typedTestJSON = `{"ok": true, "queue": 12, "ratio": 0.5, "owner": null, "meta": {"a": 1, "b": 2, "c": 3}, "items": [{"id": 1}, {"id": 2}]}`
+
+var jsonPathTypedTestCases = []struct {
+	selector string
+	expect   config.Expect
+	ok       bool
+	message  string
+}{
+	{`$.ok+`, config.Expect{Equals: true}, true, "bool"},
+	{`$.ok+`, config.Expect{Equals: "true"}, true, "bool compared to string"},
+	{`$.queue+`, config.Expect{Equals: 12}, true, "integer"},
+	{`$.queue+`, config.Expect{Lte: float(10)}, false, "integer lte"},
+	{`$.ratio+`, config.Expect{Equals: 0.5}, true, "float"},
+	{`$.owner+`, config.Expect{EqualsSet: true}, true, "null"},
+	{`$.owner+`, config.Expect{Equals: "null"}, false, "null compared to string"},
+	{`$.ok+`, config.Expect{NotEqualsSet: true}, true, "not null"},
+	{`$.owner+`, config.Expect{Count: &[]int64{0}[0]}, true, "null is empty"},
+	{`$.meta+`, config.Expect{Min: &[]int64{2}[0], Max: &[]int64{3}[0]}, true, "object key count"},
+	{`$.meta+`, config.Expect{Max: &[]int64{2}[0]}, false, "object key count max"},
+	{`$.items+`, config.Expect{Count: &[]int64{2}[0]}, true, "array length"},
+	{`$.items[*].id+`, config.Expect{Count: &[]int64{2}[0], Gt: float(0)}, true, "wildcard results"},
+	{`$.items[*].id+`, config.Expect{Gt: float(1)}, false, "wildcard results all have to match"},
+}
+
+func TestJSONPathTypedValues(t *testing.T) {
+	for _, test := range jsonPathTypedTestCases {
+		if ok, info := JSONPath([]byte(typedTestJSON), test.selector, test.expect); ok != test.ok {
+			t.Error(test.message, info)
+		}
+	}
+}
+
+func TestJSONPathInfoContainsActualValue(t *testing.T) {
+	_, info := JSONPath([]byte(typedTestJSON), `$.queue+`, config.Expect{Lt: float(10)})
+	if !strings.Contains(info, "actual json: 12") {
+		t.Error("info should contain the actual json value, got:", info)
+	}
+}
@@ -0,0 +1,79 @@
+package check
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/JumboInteractiveLimited/jsonpath"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// SchemaViolation is a single violation of a JSON schema
+type SchemaViolation struct {
+	// JSON pointer to the violating value, "" is the whole (sub) document
+	Pointer     string
+	Description string
+}
+
+// JSONSchema validates a JSON document or the sub document selected by an optional json path against a schema
+func JSONSchema(jsonBytes []byte, selector string, schema interface{}) (violations []SchemaViolation, err error) {
+	if selector != "" {
+		subDocument, errSelect := selectJSON(jsonBytes, selector)
+		if errSelect != nil {
+			return nil, errSelect
+		}
+		jsonBytes = subDocument
+	}
+
+	result, errValidate := gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewBytesLoader(jsonBytes))
+	if errValidate != nil {
+		return nil, errors.New("could not validate json schema: " + errValidate.Error())
+	}
+	for _, resultErr := range result.Errors() {
+		violations = append(violations, SchemaViolation{
+			Pointer:     jsonPointer(resultErr.Context()),
+			Description: resultErr.Description(),
+		})
+	}
+	return violations, nil
+}
+
+// selectJSON returns the raw JSON of the first result of a json path
+func selectJSON(jsonBytes []byte, selector string) (subDocument []byte, err error) {
+	paths, errParsePaths := jsonpath.ParsePaths(selector)
+	if errParsePaths != nil {
+		return nil, errors.New("could not parse json paths : " + errParsePaths.Error())
+	}
+	eval, errEval := jsonpath.EvalPathsInBytes(jsonBytes, paths)
+	if errEval != nil {
+		return nil, errors.New("error in json path : " + errEval.Error())
+	}
+	result, evalOK := eval.Next()
+	if eval.Error != nil {
+		return nil, errors.New("could not evaluate json path " + eval.Error.Error())
+	}
+	if !evalOK || len(result.Value) == 0 {
+		return nil, errors.New("no result for " + selector)
+	}
+	return result.Value, nil
+}
+
+// jsonPointer converts a validation context like (root).items.0 to a JSON pointer like /items/0
+func jsonPointer(context *gojsonschema.JsonContext) string {
+	if context == nil {
+		return ""
+	}
+	// a delimiter, that can not be part of a key allows to escape every key
+	const delimiter = "\x00"
+	parts := strings.Split(context.String(delimiter), delimiter)
+	return jsonPointerFromKeys(parts[1:])
+}
+
+// jsonPointerFromKeys escapes and joins keys and array indexes to a JSON pointer
+func jsonPointerFromKeys(keys []string) string {
+	pointer := ""
+	for _, key := range keys {
+		pointer += "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+	}
+	return pointer
+}
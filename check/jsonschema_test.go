@@ -0,0 +1,56 @@
+package check
+
+import (
+	"testing"
+)
+
+var productSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"id", "items"},
+	"properties": map[string]interface{}{
+		"id": map[string]interface{}{"type": "integer"},
+		"items": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"a/b": map[string]interface{}{"type": "string"}},
+			},
+		},
+	},
+}
+
+func TestJSONSchema(t *testing.T) {
+	violations, err := JSONSchema([]byte(`{"id": 1, "items": [{"a/b": "x"}]}`), "", productSchema)
+	if err != nil || len(violations) != 0 {
+		t.Error("valid document", err, violations)
+	}
+
+	violations, err = JSONSchema([]byte(`{"id": "1", "items": [{"a/b": "x"}, {"a/b": 2}]}`), "", productSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointers := map[string]bool{}
+	for _, violation := range violations {
+		pointers[violation.Pointer] = true
+	}
+	if len(violations) != 2 || !pointers["/id"] || !pointers["/items/1/a~1b"] {
+		t.Error("unexpected violations", violations)
+	}
+
+	violations, err = JSONSchema([]byte(`{"id": 1}`), "", productSchema)
+	if err != nil || len(violations) != 1 || violations[0].Pointer != "" {
+		t.Error("missing required property should be reported on the document", err, violations)
+	}
+}
+
+func TestJSONSchemaSubDocument(t *testing.T) {
+	schema := map[string]interface{}{"type": "integer"}
+	violations, err := JSONSchema([]byte(`{"data": {"count": 3}}`), "$.data.count+", schema)
+	if err != nil || len(violations) != 0 {
+		t.Error("valid sub document", err, violations)
+	}
+	_, err = JSONSchema([]byte(`{"data": {"count": 3}}`), "$.nope+", schema)
+	if err == nil {
+		t.Error("missing sub document should be an error")
+	}
+}
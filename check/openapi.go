@@ -0,0 +1,150 @@
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// OpenAPIDocument validates responses against the operations of an OpenAPI 3 document
+type OpenAPIDocument struct {
+	swagger  *openapi3.Swagger
+	basePath string
+}
+
+// NewOpenAPIDocument parses an OpenAPI 3 document in JSON or yaml
+// the base path is stripped from request paths before looking up operations, it defaults to the path of the first server
+func NewOpenAPIDocument(document []byte, basePath string) (doc *OpenAPIDocument, err error) {
+	swagger, errLoad := openapi3.NewSwaggerLoader().LoadSwaggerFromData(document)
+	if errLoad != nil {
+		return nil, errors.New("could not load OpenAPI document: " + errLoad.Error())
+	}
+	if basePath == "" && len(swagger.Servers) > 0 {
+		serverURL, errURL := url.Parse(swagger.Servers[0].URL)
+		if errURL == nil && !strings.Contains(serverURL.Path, "{") {
+			basePath = serverURL.Path
+		}
+	}
+	return &OpenAPIDocument{
+		swagger:  swagger,
+		basePath: strings.TrimSuffix(basePath, "/"),
+	}, nil
+}
+
+// ValidateResponse checks the status code, the content type and the body of a response against the documented operation
+func (doc *OpenAPIDocument) ValidateResponse(method, path string, response *http.Response, body []byte) (violations []SchemaViolation) {
+	operationPath := strings.TrimPrefix(path, doc.basePath)
+	template, pathItem := doc.findPath(operationPath)
+	if pathItem == nil {
+		return []SchemaViolation{{Description: "path " + operationPath + " is not documented"}}
+	}
+	operation := pathItem.GetOperation(strings.ToUpper(method))
+	if operation == nil {
+		return []SchemaViolation{{Description: "method " + method + " is not documented for " + template}}
+	}
+	operationName := strings.ToUpper(method) + " " + template
+
+	responseRef := operation.Responses.Get(response.StatusCode)
+	if responseRef == nil {
+		responseRef = operation.Responses[strconv.Itoa(response.StatusCode/100)+"XX"]
+	}
+	if responseRef == nil {
+		responseRef = operation.Responses.Default()
+	}
+	if responseRef == nil || responseRef.Value == nil {
+		return []SchemaViolation{{Description: "status code " + strconv.Itoa(response.StatusCode) + " is not documented for " + operationName}}
+	}
+	if len(responseRef.Value.Content) == 0 {
+		return nil
+	}
+
+	contentType := response.Header.Get("Content-Type")
+	mediaType := responseRef.Value.Content.Get(contentType)
+	if mediaType == nil {
+		return []SchemaViolation{{Description: "content type \"" + contentType + "\" is not documented for " + operationName + " " + strconv.Itoa(response.StatusCode)}}
+	}
+	if mediaType.Schema == nil || mediaType.Schema.Value == nil || !isJSONMediaType(contentType) {
+		return nil
+	}
+
+	var value interface{}
+	errUnmarshal := json.Unmarshal(body, &value)
+	if errUnmarshal != nil {
+		return []SchemaViolation{{Description: "could not parse response body: " + errUnmarshal.Error()}}
+	}
+	errVisit := mediaType.Schema.Value.VisitJSON(value, openapi3.MultiErrors(), openapi3.VisitAsResponse())
+	return schemaViolations(errVisit)
+}
+
+// findPath looks up the path template for a path, templates with more literal segments win
+func (doc *OpenAPIDocument) findPath(path string) (template string, pathItem *openapi3.PathItem) {
+	templates := []string{}
+	for t := range doc.swagger.Paths {
+		templates = append(templates, t)
+	}
+	sort.Strings(templates)
+	bestLiterals := -1
+	for _, t := range templates {
+		literals, match := matchPathTemplate(t, path)
+		if match && literals > bestLiterals {
+			template, pathItem, bestLiterals = t, doc.swagger.Paths[t], literals
+		}
+	}
+	return
+}
+
+// matchPathTemplate matches a path like /products/123 to a template like /products/{id}
+func matchPathTemplate(template, path string) (literals int, match bool) {
+	templateSegments := strings.Split(strings.Trim(template, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(templateSegments) != len(pathSegments) {
+		return 0, false
+	}
+	for i, segment := range templateSegments {
+		switch {
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			if pathSegments[i] == "" {
+				return 0, false
+			}
+		case segment == pathSegments[i]:
+			literals++
+		default:
+			return 0, false
+		}
+	}
+	return literals, true
+}
+
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, errParse := mime.ParseMediaType(contentType)
+	return errParse == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// schemaViolations flattens schema errors
+func schemaViolations(err error) (violations []SchemaViolation) {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case openapi3.MultiError:
+		for _, nested := range e {
+			violations = append(violations, schemaViolations(nested)...)
+		}
+	case *openapi3.SchemaError:
+		pointer := jsonPointerFromKeys(e.JSONPointer())
+		description := e.Reason
+		if description == "" {
+			description = e.Error()
+		}
+		violations = append(violations, SchemaViolation{Pointer: pointer, Description: description})
+	default:
+		violations = append(violations, SchemaViolation{Description: err.Error()})
+	}
+	return violations
+}
@@ -0,0 +1,92 @@
+package check
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const petstoreOpenAPI = `
+openapi: 3.0.0
+info:
+  title: petstore
+  version: "1"
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /pets/{id}:
+    get:
+      responses:
+        "200":
+          description: a pet
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Pet"
+        "404":
+          description: not found
+  /pets/mine:
+    get:
+      responses:
+        "200":
+          description: my pet
+          content:
+            text/plain:
+              schema:
+                type: string
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: integer
+        name:
+          type: string
+`
+
+func openAPITestResponse(statusCode int, contentType string) *http.Response {
+	resp := httptest.NewRecorder()
+	resp.Header().Set("Content-Type", contentType)
+	resp.WriteHeader(statusCode)
+	return resp.Result()
+}
+
+var openAPITestCases = []struct {
+	method      string
+	path        string
+	statusCode  int
+	contentType string
+	body        string
+	pointers    []string
+	message     string
+}{
+	{"GET", "/v1/pets/1", 200, "application/json; charset=utf-8", `{"id": 1, "name": "rex"}`, nil, "valid"},
+	{"GET", "/v1/pets/1", 404, "", ``, nil, "documented status without content"},
+	{"GET", "/v1/pets/1", 500, "application/json", `{}`, []string{""}, "undocumented status"},
+	{"GET", "/v1/pets/1", 200, "text/html", `<html>`, []string{""}, "undocumented content type"},
+	{"GET", "/v1/pets/1", 200, "application/json", `{"id": "1"}`, []string{"/id", "/name"}, "schema violations"},
+	{"POST", "/v1/pets/1", 200, "application/json", `{}`, []string{""}, "undocumented method"},
+	{"GET", "/v1/cats/1", 200, "application/json", `{}`, []string{""}, "undocumented path"},
+	{"GET", "/v1/pets/mine", 200, "text/plain", `rex`, nil, "literal path wins over template"},
+}
+
+func TestOpenAPIValidateResponse(t *testing.T) {
+	doc, errDoc := NewOpenAPIDocument([]byte(petstoreOpenAPI), "")
+	if errDoc != nil {
+		t.Fatal(errDoc)
+	}
+	for _, test := range openAPITestCases {
+		violations := doc.ValidateResponse(test.method, test.path, openAPITestResponse(test.statusCode, test.contentType), []byte(test.body))
+		if len(violations) != len(test.pointers) {
+			t.Error(test.message, violations)
+			continue
+		}
+		for i, violation := range violations {
+			if violation.Pointer != test.pointers[i] {
+				t.Error(test.message, "unexpected pointer", violation)
+			}
+		}
+	}
+}
@@ -3,7 +3,6 @@ package check
 import (
 	"github.com/foomo/petze/config"
 	"regexp"
-	"strings"
 )
 
 func Regex(data []byte, selector string, expect config.Expect) (ok bool, info string) {
@@ -14,24 +13,15 @@ func Regex(data []byte, selector string, expect config.Expect) (ok bool, info st
 	}
 
 	res := regex.FindAll(data, -1)
-	switch true {
-	case expect.Min != nil || expect.Max != nil || expect.Count != nil:
-		return checkMinMaxCount(expect, int64(len(res)))
-	case expect.Equals != nil:
-		for _, res := range res {
-			if string(res) == expect.Equals {
-				return true, "regex match found"
-			}
-		}
-		return false, "could not find regex result equals"
-	case expect.Contains != "":
-		for _, res := range res {
-			if strings.Contains(string(res), expect.Contains) {
-				return true, "regex contains substring found"
-			}
-		}
-		return false, "could not find regex result contains"
-	default:
-		return false, "comparator not implemented for regex"
+	sel := selection{
+		matches: int64(len(res)),
+		length:  int64(len(res)),
+		values:  make([]interface{}, len(res)),
+		// one matching regex result is sufficient
+		any: true,
 	}
+	for i, r := range res {
+		sel.values[i] = string(r)
+	}
+	return evaluate(expect, sel)
 }
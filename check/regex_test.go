@@ -88,3 +88,15 @@ func expectMax(max int) config.Expect {
 func expectCount(count int) config.Expect {
 	return config.Expect{Max: &[]int64{int64(count)}[0]}
 }
+
+func TestCheckRegexCombined(t *testing.T) {
+	data := []byte("<li>sku-1</li><li>sku-2</li><li>sku-3</li>")
+	expect := config.Expect{Between: []int64{1, 5}, Contains: "sku-3"}
+	if ok, info := Regex(data, `sku-\d`, expect); !ok {
+		t.Error("between and contains should both match", info)
+	}
+	expect = config.Expect{Between: []int64{1, 2}, Contains: "sku-3"}
+	if ok, _ := Regex(data, `sku-\d`, expect); ok {
+		t.Error("between must be enforced next to contains")
+	}
+}
@@ -0,0 +1,61 @@
+package check
+
+import (
+	"io"
+
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/foomo/petze/config"
+)
+
+// XPathDocument is a parsed xml or html document to evaluate xpath expressions on
+type XPathDocument struct {
+	newNavigator func() xpath.NodeNavigator
+}
+
+// NewXMLDocument parses xml e.g. from SOAP services, RSS / Atom feeds or sitemaps
+func NewXMLDocument(r io.Reader) (doc *XPathDocument, err error) {
+	root, errParse := xmlquery.Parse(r)
+	if errParse != nil {
+		return nil, errParse
+	}
+	return &XPathDocument{newNavigator: func() xpath.NodeNavigator {
+		return xmlquery.CreateXPathNavigator(root)
+	}}, nil
+}
+
+// NewHTMLDocument parses html
+func NewHTMLDocument(r io.Reader) (doc *XPathDocument, err error) {
+	root, errParse := htmlquery.Parse(r)
+	if errParse != nil {
+		return nil, errParse
+	}
+	return &XPathDocument{newNavigator: func() xpath.NodeNavigator {
+		return htmlquery.CreateXPathNavigator(root)
+	}}, nil
+}
+
+// XPath checks an xpath expression - node sets are evaluated per node, functions like count() or string() by their result
+func XPath(doc *XPathDocument, selector string, expect config.Expect) (ok bool, info string) {
+	expr, errCompile := xpath.Compile(selector)
+	if errCompile != nil {
+		return false, "could not compile xpath '" + selector + "' : " + errCompile.Error()
+	}
+
+	sel := selection{}
+	switch result := expr.Evaluate(doc.newNavigator()).(type) {
+	case *xpath.NodeIterator:
+		for result.MoveNext() {
+			sel.values = append(sel.values, result.Current().Value())
+		}
+		sel.matches = int64(len(sel.values))
+		sel.length = int64(len(sel.values))
+	default:
+		// number, string or boolean
+		sel.matches = 1
+		sel.length = 1
+		sel.values = []interface{}{result}
+	}
+	return evaluate(expect, sel)
+}
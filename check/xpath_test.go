@@ -0,0 +1,59 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/foomo/petze/config"
+)
+
+const sitemapTestXML = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset>
+  <url><loc>https://www.example.com/</loc><priority>1.0</priority></url>
+  <url><loc>https://www.example.com/shoes</loc><priority>0.8</priority></url>
+</urlset>`
+
+const soapTestXML = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body><GetStockResponse><Stock available="true">12</Stock></GetStockResponse></soap:Body>
+</soap:Envelope>`
+
+var xPathTestCases = []struct {
+	doc      string
+	selector string
+	expect   config.Expect
+	ok       bool
+	message  string
+}{
+	{sitemapTestXML, `//url/loc`, config.Expect{Count: &[]int64{2}[0]}, true, "count nodes"},
+	{sitemapTestXML, `//url/loc`, config.Expect{Matches: `^https://www\.example\.com/`}, true, "all nodes match"},
+	{sitemapTestXML, `//url/priority`, config.Expect{Gte: float(0.8)}, true, "numeric node values"},
+	{sitemapTestXML, `count(//url)`, config.Expect{Equals: 2}, true, "count function"},
+	{sitemapTestXML, `//url/lastmod`, config.Expect{Absent: true}, true, "absent"},
+	{soapTestXML, `//GetStockResponse/Stock`, config.Expect{Gt: float(0)}, true, "soap value"},
+	{soapTestXML, `//Stock/@available`, config.Expect{Equals: true}, true, "attribute"},
+	{soapTestXML, `//Fault`, config.Expect{Exists: true}, false, "exists"},
+	{soapTestXML, `//[`, config.Expect{Exists: true}, false, "invalid xpath"},
+}
+
+func TestXPath(t *testing.T) {
+	for _, test := range xPathTestCases {
+		doc, errDoc := NewXMLDocument(strings.NewReader(test.doc))
+		if errDoc != nil {
+			t.Fatal(errDoc)
+		}
+		if ok, info := XPath(doc, test.selector, test.expect); ok != test.ok {
+			t.Error(test.message, info)
+		}
+	}
+}
+
+func TestXPathHTML(t *testing.T) {
+	doc, errDoc := NewHTMLDocument(strings.NewReader(seoTestHTML))
+	if errDoc != nil {
+		t.Fatal(errDoc)
+	}
+	if ok, info := XPath(doc, `//link[@rel="canonical"]/@href`, config.Expect{Equals: "https://www.example.com/shoes"}); !ok {
+		t.Error(info)
+	}
+}
@@ -1,25 +1,35 @@
 package collector
 
 import (
-	"encoding/json"
-	"time"
+	"context"
+	"sync"
 
 	"github.com/foomo/petze/config"
 	"github.com/foomo/petze/watch"
-
-	log "github.com/sirupsen/logrus"
 )
 
 type ResultListener func(watch.Result)
 
 // Collector collects stats on services
 type Collector struct {
+	ctx               context.Context
+	cancel            context.CancelFunc
+	running           sync.WaitGroup
 	servicesConfigDir string
-	chanServices      chan map[string]*config.Service
+	chanReload        chan reload
+	chanTrigger       chan struct{}
+	chanConfigResult  chan watch.Result
 	chanGetResults    chan map[string][]watch.Result
 	watchers          map[string]*watch.Watcher
 	resultListeners   []ResultListener
+	serverListeners   []func() error
 	services          map[string]*config.Service
+	scheduler         *scheduler
+
+	// owned by the config watch
+	configWatcher   *watch.Watcher
+	configFailed    bool
+	errServerConfig error
 }
 
 // NewCollector construct a collector - it will watch its config files for changes
@@ -27,21 +37,38 @@ func NewCollector(servicesConfigDir string) (c *Collector, err error) {
 	c = &Collector{
 		servicesConfigDir: servicesConfigDir,
 		services:          make(map[string]*config.Service),
-		chanServices:      make(chan map[string]*config.Service),
+		chanReload:        make(chan reload),
+		chanTrigger:       make(chan struct{}, 1),
+		chanConfigResult:  make(chan watch.Result),
 		chanGetResults:    make(chan map[string][]watch.Result),
 		watchers:          make(map[string]*watch.Watcher),
 		resultListeners:   make([]ResultListener, 0),
+		scheduler:         newScheduler(defaultMaxSessions, defaultMaxSessionsPerHost),
+		configWatcher:     watch.NewConfigWatcher(),
 	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
 
 	return c, nil
 }
 
 // Starts collection of results and configuration watch
 func (c *Collector) Start() {
+	c.running.Add(2)
 	go c.collect()
 	go c.configWatch()
 }
 
+// SetConcurrencyLimits limits how many sessions run at the same time in total and per host, 0 keeps the default - call it before Start
+func (c *Collector) SetConcurrencyLimits(maxSessions, maxSessionsPerHost int) {
+	c.scheduler = newScheduler(maxSessions, maxSessionsPerHost)
+}
+
+// Stop the configuration watch and all watchers - running sessions are cancelled
+func (c *Collector) Stop() {
+	c.cancel()
+	c.running.Wait()
+}
+
 const maxResults = 1000
 
 func (c *Collector) RegisterListener(listener ResultListener) {
@@ -55,63 +82,51 @@ func (c *Collector) NotifyListeners(result watch.Result) {
 }
 
 func (c *Collector) collect() {
+	defer c.running.Done()
 
 	chanResult := make(chan watch.Result)
 	results := map[string][]watch.Result{}
 
 	for {
 		select {
+		case <-c.ctx.Done():
+			for watcherID, watcher := range c.watchers {
+				watcher.Stop()
+				delete(c.watchers, watcherID)
+			}
+			return
 		case <-c.chanGetResults:
 			resultsCopy := map[string][]watch.Result{}
 			for name, results := range results {
 				resultsCopy[name] = results
 			}
 			c.chanGetResults <- resultsCopy
-		case newServices := <-c.chanServices:
-			c.services = newServices
-
-			var lastErrors = make(map[string][]watch.Error)
-
-			// stop old watchers
-			for oldWatcherID, oldWatcher := range c.watchers {
+		case reload := <-c.chanReload:
+			for _, serviceID := range reload.changes.Removed {
+				c.watchers[serviceID].Stop()
+				delete(c.watchers, serviceID)
+				delete(c.services, serviceID)
+				delete(results, serviceID)
+			}
+			for _, serviceID := range reload.changes.Changed {
+				oldWatcher := c.watchers[serviceID]
 				oldWatcher.Stop()
-
-				// if the service had errors before updating the config
-				// store them in a map so we can transfer them to the updated watchers
-				if len(oldWatcher.LastErrors()) > 0 {
-					lastErrors[oldWatcherID] = oldWatcher.LastErrors()
-				}
-
-				delete(c.watchers, oldWatcherID)
+				// transfer errors to the new watcher, so that notifications are not repeated
+				c.startWatcher(reload.services[serviceID], oldWatcher.LastErrors(), chanResult)
 			}
-
-			// setup new watchers
-			for serviceID, service := range c.services {
-				// check if the service had errors before being updated
-				lastErrs, ok := lastErrors[serviceID]
-				if ok {
-					// transfer errors to new watcher
-					newWatcher := watch.Watch(service, chanResult)
-					newWatcher.SetLastErrors(lastErrs)
-					c.watchers[serviceID] = newWatcher
-				} else {
-					// no errors - init a new watcher
-					c.watchers[serviceID] = watch.Watch(service, chanResult)
-				}
-				// reset stored results
-				_, ok = results[serviceID]
-				if !ok {
-					results[serviceID] = []watch.Result{}
-				}
+			for _, serviceID := range reload.changes.Added {
+				c.startWatcher(reload.services[serviceID], nil, chanResult)
+				results[serviceID] = []watch.Result{}
 			}
-			// clean up results
-			for possiblyUnknownServiceID := range results {
-				_, ok := c.watchers[possiblyUnknownServiceID]
-				if !ok {
-					// clean up results
-					delete(results, possiblyUnknownServiceID)
-				}
+			// unchanged services keep their watchers with their timing and notification state
+		case result := <-c.chanConfigResult:
+			// the configuration error is listed with the services, until it is resolved
+			if len(result.Errors) > 0 {
+				results[watch.ConfigID] = []watch.Result{result}
+			} else {
+				delete(results, watch.ConfigID)
 			}
+			c.NotifyListeners(result)
 		case result := <-chanResult:
 			serviceResults, ok := results[result.ID]
 			if ok {
@@ -129,43 +144,21 @@ func (c *Collector) collect() {
 
 // GetResults get current results
 func (c *Collector) GetResults() map[string][]watch.Result {
-	c.chanGetResults <- nil
-	return <-c.chanGetResults
-}
-
-func hashServiceConfig(config map[string]*config.Service) (hash string) {
-	hash = "invalid config"
-	jsonBytes, errJSON := json.Marshal(config)
-	if errJSON == nil {
-		hash = string(jsonBytes)
-	}
-	return hash
-}
-
-func (c *Collector) configWatch() {
-	for {
-		services, errServices := config.LoadServices(c.servicesConfigDir)
-		if errServices != nil {
-			log.Error("could not read configuration:", errServices)
-		}
-		if errServices == nil {
-			newHash := hashServiceConfig(services)
-			oldHash := hashServiceConfig(c.services)
-			if newHash != oldHash {
-				log.Info("configuration update successful")
-				c.updateServices()
-			}
-		}
-		time.Sleep(10 * time.Second)
+	select {
+	case c.chanGetResults <- nil:
+		return <-c.chanGetResults
+	case <-c.ctx.Done():
+		return map[string][]watch.Result{}
 	}
 }
 
-func (c *Collector) updateServices() error {
-	services, err := config.LoadServices(c.servicesConfigDir)
-	if err == nil {
-		c.chanServices <- services
-	} else {
-		log.Warn("could not update services:", err)
+func (c *Collector) startWatcher(service *config.Service, lastErrors []watch.Error, chanResult chan watch.Result) {
+	newWatcher := watch.NewWatcher(service)
+	newWatcher.SetScheduler(c.scheduler)
+	if len(lastErrors) > 0 {
+		newWatcher.SetLastErrors(lastErrors)
 	}
-	return err
+	newWatcher.Start(chanResult)
+	c.watchers[service.ID] = newWatcher
+	c.services[service.ID] = service
 }
@@ -1,8 +1,15 @@
 package collector
 
 import (
-	"github.com/foomo/petze/watch"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/foomo/petze/watch"
 )
 
 func TestCollectorListeners(t *testing.T) {
@@ -19,3 +26,164 @@ func TestCollectorListeners(t *testing.T) {
 		t.Error("actual result is not equal to the expected result")
 	}
 }
+
+func TestCollectorStop(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "petze-collector")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "service.yml"), []byte("endpoint: http://127.0.0.1:1\ninterval: 1h\n"), 0644)
+
+	c, _ := NewCollector(dir)
+	c.Start()
+	stopped := make(chan struct{})
+	go func() {
+		c.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector did not stop")
+	}
+	if results := c.GetResults(); len(results) != 0 {
+		t.Error("a stopped collector has no results", results)
+	}
+}
+
+func waitForResults(c *Collector, ok func(map[string][]watch.Result) bool) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if ok(c.GetResults()) {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return false
+}
+
+func TestCollectorReloadOnChange(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "petze-collector")
+	defer os.RemoveAll(dir)
+
+	c, _ := NewCollector(dir)
+	c.Start()
+	defer c.Stop()
+
+	// services in new folders are picked up as well
+	os.Mkdir(filepath.Join(dir, "team"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "team", "service.yml"), []byte("endpoint: http://127.0.0.1:1\ninterval: 1h\n"), 0644)
+	if !waitForResults(c, func(results map[string][]watch.Result) bool {
+		_, ok := results["team/service"]
+		return ok
+	}) {
+		t.Fatal("an added service was not loaded")
+	}
+
+	os.Remove(filepath.Join(dir, "team", "service.yml"))
+	if !waitForResults(c, func(results map[string][]watch.Result) bool {
+		return len(results) == 0
+	}) {
+		t.Fatal("a removed service was not dropped")
+	}
+}
+
+func TestCollectorServerConfigChange(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "petze-collector")
+	defer os.RemoveAll(dir)
+
+	c, _ := NewCollector(dir)
+	changes := make(chan struct{}, 10)
+	c.OnServerConfigChange(func() error {
+		changes <- struct{}{}
+		return nil
+	})
+	c.Start()
+	defer c.Stop()
+
+	expectChange := func(reason string) {
+		select {
+		case <-changes:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no server configuration change after ", reason)
+		}
+	}
+
+	c.Reload()
+	expectChange("a triggered reload")
+
+	ioutil.WriteFile(filepath.Join(dir, "petze.yml"), []byte("address: 127.0.0.1:8080\n"), 0644)
+	expectChange("writing petze.yml")
+}
+
+func TestCollectorConfigError(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "petze-collector")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "service.yml"), []byte("endpoint: http://127.0.0.1:1\ninterval: 1h\ntypo: true\n"), 0644)
+
+	c, _ := NewCollector(dir)
+	resolved := make(chan struct{}, 10)
+	c.RegisterListener(func(result watch.Result) {
+		if result.ID == watch.ConfigID && len(result.Errors) == 0 {
+			resolved <- struct{}{}
+		}
+	})
+	c.Start()
+	defer c.Stop()
+
+	if !waitForResults(c, func(results map[string][]watch.Result) bool {
+		configResults := results[watch.ConfigID]
+		return len(configResults) == 1 && len(configResults[0].Errors) == 1 && configResults[0].Errors[0].Type == watch.ErrorTypeConfig
+	}) {
+		t.Fatal("the configuration error was not reported")
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "service.yml"), []byte("endpoint: http://127.0.0.1:1\ninterval: 1h\n"), 0644)
+	if !waitForResults(c, func(results map[string][]watch.Result) bool {
+		_, failed := results[watch.ConfigID]
+		_, loaded := results["service"]
+		return !failed && loaded
+	}) {
+		t.Fatal("the configuration error was not cleared")
+	}
+	select {
+	case <-resolved:
+	case <-time.After(time.Second):
+		t.Error("listeners were not told, that the configuration error was resolved")
+	}
+}
+
+func TestCollectorServerConfigError(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "petze-collector")
+	defer os.RemoveAll(dir)
+
+	c, _ := NewCollector(dir)
+	var (
+		mutex     sync.Mutex
+		errReload = errors.New("could not reload server configuration")
+	)
+	c.OnServerConfigChange(func() error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return errReload
+	})
+	c.Start()
+	defer c.Stop()
+
+	c.Reload()
+	if !waitForResults(c, func(results map[string][]watch.Result) bool {
+		configResults := results[watch.ConfigID]
+		return len(configResults) == 1 && len(configResults[0].Errors) == 1 && configResults[0].Errors[0].Error == errReload.Error()
+	}) {
+		t.Fatal("the server configuration error was not reported")
+	}
+
+	mutex.Lock()
+	errReload = nil
+	mutex.Unlock()
+	c.Reload()
+	if !waitForResults(c, func(results map[string][]watch.Result) bool {
+		_, failed := results[watch.ConfigID]
+		return !failed
+	}) {
+		t.Fatal("the server configuration error was not cleared")
+	}
+}
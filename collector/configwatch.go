@@ -0,0 +1,183 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/foomo/petze/config"
+	"github.com/foomo/petze/watch"
+	"github.com/fsnotify/fsnotify"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// editors and deployments write several files at once, their events are collected into one reload
+	reloadDebounce = 500 * time.Millisecond
+	// fallback, when file system events are not available
+	pollInterval = 10 * time.Second
+)
+
+// Reload loads the configuration again, as if it had changed on disk - e.g. on SIGHUP
+func (c *Collector) Reload() {
+	select {
+	case c.chanTrigger <- struct{}{}:
+	default:
+		// a reload is pending already
+	}
+}
+
+// OnServerConfigChange registers a listener for changes of petze.yml and for triggered reloads - call it before Start
+// errors of listeners are reported like configuration errors of services, until the next change succeeds
+func (c *Collector) OnServerConfigChange(listener func() error) {
+	c.serverListeners = append(c.serverListeners, listener)
+}
+
+func (c *Collector) configWatch() {
+	defer c.running.Done()
+
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+	)
+	watcher, errWatcher := fsnotify.NewWatcher()
+	if errWatcher != nil {
+		log.Warn("could not watch the configuration, polling every ", pollInterval, " : ", errWatcher)
+	} else {
+		defer watcher.Close()
+		addWatches(watcher, c.servicesConfigDir)
+		events, errs = watcher.Events, watcher.Errors
+	}
+
+	// the services of the last successful load
+	loaded := map[string]*config.Service{}
+	serverConfigChanged := false
+	for {
+		if serverConfigChanged {
+			c.errServerConfig = nil
+			for _, listener := range c.serverListeners {
+				if errListener := listener(); errListener != nil {
+					log.Error(errListener)
+					c.errServerConfig = errListener
+				}
+			}
+			serverConfigChanged = false
+		}
+		var running bool
+		loaded, running = c.loadServices(loaded)
+		if !running {
+			return
+		}
+
+		var poll, debounce <-chan time.Time
+		if watcher == nil {
+			poll = time.After(pollInterval)
+		}
+	wait:
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-poll:
+				break wait
+			case <-debounce:
+				break wait
+			case <-c.chanTrigger:
+				serverConfigChanged = true
+				break wait
+			case event := <-events:
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				if event.Op&fsnotify.Create != 0 {
+					// new folders have to be watched as well
+					if info, errStat := os.Stat(event.Name); errStat == nil && info.IsDir() {
+						addWatches(watcher, event.Name)
+					}
+				}
+				if filepath.Base(event.Name) == config.ServerConfigFile {
+					serverConfigChanged = true
+				}
+				debounce = time.After(reloadDebounce)
+			case errWatch := <-errs:
+				log.Warn("configuration watch: ", errWatch)
+			}
+		}
+	}
+}
+
+// loadServices sends the changes to collect and returns the services, that are collected now
+func (c *Collector) loadServices(loaded map[string]*config.Service) (services map[string]*config.Service, running bool) {
+	services, errServices := config.LoadServices(c.servicesConfigDir)
+	if errServices != nil {
+		log.Error("could not read configuration:", errServices)
+	}
+	if !c.reportConfigState(errServices) {
+		return loaded, false
+	}
+	if errServices != nil {
+		return loaded, true
+	}
+	changes := diffServices(loaded, services)
+	if changes.Empty() {
+		return loaded, true
+	}
+	log.WithFields(log.Fields{
+		"added":   changes.Added,
+		"changed": changes.Changed,
+		"removed": changes.Removed,
+	}).Info("configuration update successful")
+	select {
+	case c.chanReload <- reload{services: services, changes: changes}:
+		return services, true
+	case <-c.ctx.Done():
+		return loaded, false
+	}
+}
+
+// addWatches watches dir and all folders below it
+func addWatches(watcher *fsnotify.Watcher, dir string) {
+	filepath.Walk(dir, func(fp string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if errAdd := watcher.Add(fp); errAdd != nil {
+				log.Warn("could not watch ", fp, " : ", errAdd)
+			}
+		}
+		return nil
+	})
+}
+
+// reportConfigState reports errors of petze.yml and of the services, or that they were resolved
+func (c *Collector) reportConfigState(errServices error) bool {
+	errs := []error{}
+	for _, err := range []error{c.errServerConfig, errServices} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	switch {
+	case len(errs) > 0:
+		c.configFailed = true
+		return c.reportConfig(watch.ConfigResult(errs...))
+	case c.configFailed:
+		log.Info("configuration error resolved")
+		c.configFailed = false
+		return c.reportConfig(watch.ConfigResult())
+	}
+	return true
+}
+
+// reportConfig notifies about the state of the configuration and hands it to the collection, it returns false once the collector was stopped
+func (c *Collector) reportConfig(r *watch.Result) bool {
+	c.configWatcher.Notify(r)
+	select {
+	case c.chanConfigResult <- *r:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
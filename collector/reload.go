@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/foomo/petze/config"
+)
+
+// ServiceChanges are the ids of the services, that were added, changed or removed by a configuration reload
+type ServiceChanges struct {
+	Added   []string `json:"added"`
+	Changed []string `json:"changed"`
+	Removed []string `json:"removed"`
+}
+
+// Empty tells if a reload did not change anything
+func (changes ServiceChanges) Empty() bool {
+	return len(changes.Added) == 0 && len(changes.Changed) == 0 && len(changes.Removed) == 0
+}
+
+type reload struct {
+	services map[string]*config.Service
+	changes  ServiceChanges
+}
+
+// diffServices compares the services one by one
+func diffServices(oldServices, newServices map[string]*config.Service) (changes ServiceChanges) {
+	for id, newService := range newServices {
+		oldService, ok := oldServices[id]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, id)
+		case hashService(oldService) != hashService(newService):
+			changes.Changed = append(changes.Changed, id)
+		}
+	}
+	for id := range oldServices {
+		if _, ok := newServices[id]; !ok {
+			changes.Removed = append(changes.Removed, id)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Changed)
+	sort.Strings(changes.Removed)
+	return changes
+}
+
+// hashService covers everything, that was loaded for a service including the contents of referenced files
+func hashService(service *config.Service) (hash string) {
+	hash = "invalid config"
+	jsonBytes, errJSON := json.Marshal(service)
+	if errJSON == nil {
+		hash = string(jsonBytes)
+	}
+	return hash
+}
@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/foomo/petze/config"
+)
+
+func testServices(endpoints map[string]string) map[string]*config.Service {
+	services := map[string]*config.Service{}
+	for id, endpoint := range endpoints {
+		services[id] = &config.Service{ID: id, Endpoint: endpoint, Interval: time.Hour}
+	}
+	return services
+}
+
+func TestDiffServices(t *testing.T) {
+	oldServices := testServices(map[string]string{"a": "http://a", "b": "http://b", "c": "http://c"})
+	newServices := testServices(map[string]string{"a": "http://a", "b": "http://b.changed", "d": "http://d"})
+	changes := diffServices(oldServices, newServices)
+	expected := ServiceChanges{Added: []string{"d"}, Changed: []string{"b"}, Removed: []string{"c"}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Error("unexpected changes", changes)
+	}
+	if !diffServices(newServices, testServices(map[string]string{"a": "http://a", "b": "http://b.changed", "d": "http://d"})).Empty() {
+		t.Error("equal services must not change")
+	}
+
+	// a plain header string compares a missing header differently than equals
+	plainServices, expectServices := testServices(map[string]string{"a": "http://a"}), testServices(map[string]string{"a": "http://a"})
+	plain := config.PlainHeaderExpect("")
+	plainServices["a"].Session = []config.Call{{Check: []config.Check{{Headers: map[string]config.HeaderExpect{"X-Test": plain}}}}}
+	expectServices["a"].Session = []config.Call{{Check: []config.Check{{Headers: map[string]config.HeaderExpect{"X-Test": {Expect: plain.Expect}}}}}}
+	if changes := diffServices(plainServices, expectServices); !reflect.DeepEqual(changes.Changed, []string{"a"}) {
+		t.Error("a changed plain header expectation has to change the service", changes)
+	}
+}
+
+func TestCollectorReload(t *testing.T) {
+	c, _ := NewCollector("")
+	c.running.Add(1)
+	go c.collect()
+	defer c.Stop()
+
+	apply := func(services map[string]*config.Service, changes ServiceChanges) {
+		c.chanReload <- reload{services: services, changes: changes}
+		// wait until the reload was applied
+		c.GetResults()
+	}
+
+	apply(testServices(map[string]string{"a": "http://127.0.0.1:1/a", "b": "http://127.0.0.1:1/b"}), ServiceChanges{Added: []string{"a", "b"}})
+	watcherA, watcherB := c.watchers["a"], c.watchers["b"]
+
+	apply(testServices(map[string]string{"a": "http://127.0.0.1:1/a", "c": "http://127.0.0.1:1/c"}), ServiceChanges{Added: []string{"c"}, Removed: []string{"b"}})
+	if c.watchers["a"] != watcherA {
+		t.Error("the watcher of an unchanged service must be kept")
+	}
+	if _, ok := c.watchers["b"]; ok {
+		t.Error("the watcher of a removed service must be stopped")
+	}
+	if c.watchers["c"] == nil || c.watchers["c"] == watcherB {
+		t.Error("missing watcher for an added service")
+	}
+
+	apply(testServices(map[string]string{"a": "http://127.0.0.1:1/changed"}), ServiceChanges{Changed: []string{"a"}})
+	if c.watchers["a"] == watcherA {
+		t.Error("the watcher of a changed service must be restarted")
+	}
+	results := c.GetResults()
+	if _, ok := results["a"]; !ok || len(results) != 2 {
+		t.Error("unexpected results", results)
+	}
+}
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"context"
+	"hash/fnv"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/foomo/petze/config"
+)
+
+// default limits of concurrently running sessions
+const (
+	defaultMaxSessions        = 32
+	defaultMaxSessionsPerHost = 4
+)
+
+// scheduler spreads the runs of the services across their intervals and limits how many sessions run at the same time
+type scheduler struct {
+	global     chan struct{}
+	maxPerHost int
+
+	mutex sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func newScheduler(maxSessions, maxSessionsPerHost int) *scheduler {
+	if maxSessions <= 0 {
+		maxSessions = defaultMaxSessions
+	}
+	if maxSessionsPerHost <= 0 {
+		maxSessionsPerHost = defaultMaxSessionsPerHost
+	}
+	return &scheduler{
+		global:     make(chan struct{}, maxSessions),
+		maxPerHost: maxSessionsPerHost,
+		hosts:      map[string]chan struct{}{},
+	}
+}
+
+// Offset is derived from the service id, so a service keeps its slot in the interval across reloads
+func (s *scheduler) Offset(service *config.Service) time.Duration {
+	if service.Interval <= 0 {
+		return 0
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(service.ID))
+	return time.Duration(hash.Sum64() % uint64(service.Interval))
+}
+
+// Acquire blocks until the service may run a session, the host slot is taken first to not block the global slots
+func (s *scheduler) Acquire(ctx context.Context, service *config.Service) (release func(), err error) {
+	host := s.hostSlots(service)
+	select {
+	case host <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case s.global <- struct{}{}:
+	case <-ctx.Done():
+		<-host
+		return nil, ctx.Err()
+	}
+	return func() {
+		<-s.global
+		<-host
+	}, nil
+}
+
+func (s *scheduler) hostSlots(service *config.Service) chan struct{} {
+	host := service.Endpoint
+	if endpointURL, errURL := url.Parse(service.Endpoint); errURL == nil {
+		host = endpointURL.Host
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	slots, ok := s.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, s.maxPerHost)
+		s.hosts[host] = slots
+	}
+	return slots
+}
@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/foomo/petze/config"
+)
+
+func TestSchedulerOffset(t *testing.T) {
+	s := newScheduler(0, 0)
+	offsets := map[time.Duration]bool{}
+	for i := 0; i < 100; i++ {
+		service := &config.Service{ID: fmt.Sprint("service-", i), Interval: time.Minute}
+		offset := s.Offset(service)
+		if offset < 0 || offset >= service.Interval {
+			t.Fatal("offset out of interval", offset)
+		}
+		if offset != s.Offset(service) {
+			t.Fatal("offsets have to be stable")
+		}
+		offsets[offset] = true
+	}
+	if len(offsets) < 90 {
+		t.Error("offsets are not spread", len(offsets))
+	}
+}
+
+func TestSchedulerLimits(t *testing.T) {
+	s := newScheduler(2, 1)
+	a1 := &config.Service{Endpoint: "http://a.example.com"}
+	a2 := &config.Service{Endpoint: "http://a.example.com/other"}
+	b := &config.Service{Endpoint: "http://b.example.com"}
+	c := &config.Service{Endpoint: "http://c.example.com"}
+
+	releaseA1, _ := s.Acquire(context.Background(), a1)
+	releaseB, _ := s.Acquire(context.Background(), b)
+
+	expectBlocked := func(service *config.Service, reason string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := s.Acquire(ctx, service); err == nil {
+			t.Error(reason)
+		}
+	}
+	expectBlocked(a2, "the per host limit was not applied")
+	expectBlocked(c, "the global limit was not applied")
+
+	releaseB()
+	releaseC, errC := s.Acquire(context.Background(), c)
+	if errC != nil {
+		t.Fatal(errC)
+	}
+	releaseA1()
+	releaseA2, errA2 := s.Acquire(context.Background(), a2)
+	if errA2 != nil {
+		t.Fatal(errA2)
+	}
+	releaseA2()
+	releaseC()
+}
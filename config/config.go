@@ -5,37 +5,200 @@ import (
 	"fmt"
 	"net/url"
 	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ContentTypeJSON    = "application/json"
+	ServerConfigFile   = "petze.yml"
+	DefaultsConfigFile = "_defaults.yml"
 )
 
+// request body encodings besides JSON
 const (
-	ContentTypeJSON  = "application/json"
-	serverConfigFile = "petze.yml"
+	ContentTypeForm      = "application/x-www-form-urlencoded"
+	ContentTypeMultipart = "multipart/form-data"
 )
 
+// modes to apply value operators to multiple elements
+const (
+	ElementsAll   = "all"
+	ElementsAny   = "any"
+	ElementsFirst = "first"
+)
+
+// Expect describes what is expected from a selection - all configured constraints have to be met
 type Expect struct {
-	Max      *int64      `yaml:"max"`
-	Min      *int64      `yaml:"min"`
-	Count    *int64      `yaml:"count"`
-	Contains string      `yaml:"contains"`
-	Equals   interface{} `yaml:"equals"`
+	// count constraints
+	Max     *int64  `yaml:"max,omitempty"`
+	Min     *int64  `yaml:"min,omitempty"`
+	Count   *int64  `yaml:"count,omitempty"`
+	Between []int64 `yaml:"between,omitempty"` // inclusive range: [min, max]
+
+	// presence
+	Exists bool `yaml:"exists,omitempty"`
+	Absent bool `yaml:"absent,omitempty"`
+
+	// value operators - applied to the extracted values
+	Contains  string        `yaml:"contains,omitempty"`
+	Equals    interface{}   `yaml:"equals,omitempty"`
+	NotEquals interface{}   `yaml:"notEquals,omitempty"`
+	Matches   string        `yaml:"matches,omitempty"`
+	OneOf     []interface{} `yaml:"oneOf,omitempty"`
+	Gt        *float64      `yaml:"gt,omitempty"`
+	Gte       *float64      `yaml:"gte,omitempty"`
+	Lt        *float64      `yaml:"lt,omitempty"`
+	Lte       *float64      `yaml:"lte,omitempty"`
+
+	// how value operators are applied to multiple values: all, any or first
+	Elements string `yaml:"elements,omitempty"`
+
+	// set, when equals or notEquals are configured - an explicit null expects a JSON null
+	EqualsSet    bool `yaml:"-"`
+	NotEqualsSet bool `yaml:"-"`
+}
+
+// UnmarshalYAML keeps track of an explicit equals: null or notEquals: null, that yaml does not tell from a missing key
+func (e *Expect) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Expect
+	if err := unmarshal((*plain)(e)); err != nil {
+		return err
+	}
+	keys := map[string]interface{}{}
+	if err := unmarshal(&keys); err != nil {
+		return err
+	}
+	_, e.EqualsSet = keys["equals"]
+	_, e.NotEqualsSet = keys["notEquals"]
+	return nil
+}
+
+// MarshalYAML leaves out unset operators, only an explicit null is written as equals: null or notEquals: null
+func (e Expect) MarshalYAML() (interface{}, error) {
+	type plain Expect
+	explicitEquals, explicitNotEquals := e.EqualsSet && e.Equals == nil, e.NotEqualsSet && e.NotEquals == nil
+	if !explicitEquals && !explicitNotEquals {
+		return plain(e), nil
+	}
+	yamlBytes, errMarshal := yaml.Marshal(plain(e))
+	if errMarshal != nil {
+		return nil, errMarshal
+	}
+	fields := yaml.MapSlice{}
+	if errUnmarshal := yaml.Unmarshal(yamlBytes, &fields); errUnmarshal != nil {
+		return nil, errUnmarshal
+	}
+	if explicitEquals {
+		fields = append(fields, yaml.MapItem{Key: "equals", Value: nil})
+	}
+	if explicitNotEquals {
+		fields = append(fields, yaml.MapItem{Key: "notEquals", Value: nil})
+	}
+	return fields, nil
+}
+
+// HasEquals tells if an equals expectation is configured
+func (e Expect) HasEquals() bool {
+	return e.Equals != nil || e.EqualsSet
+}
+
+// HasNotEquals tells if a notEquals expectation is configured
+func (e Expect) HasNotEquals() bool {
+	return e.NotEquals != nil || e.NotEqualsSet
+}
+
+// HasRange tells if any of the count constraints min, max, count or between is set
+func (e Expect) HasRange() bool {
+	return e.Min != nil || e.Max != nil || e.Count != nil || e.Between != nil
+}
+
+// HasValueConstraint tells if any of the value operators is set
+func (e Expect) HasValueConstraint() bool {
+	return e.Contains != "" || e.HasEquals() || e.HasNotEquals() || e.Matches != "" || e.OneOf != nil ||
+		e.Gt != nil || e.Gte != nil || e.Lt != nil || e.Lte != nil
+}
+
+// HeaderExpect describes what is expected from the values of a response header - a plain string expects the first value to be equal
+type HeaderExpect struct {
+	Expect `yaml:",inline"`
+	// configured as a plain string
+	Plain bool `yaml:"-" json:"plain"`
+}
+
+// PlainHeaderExpect is the plain string form - like http.Header.Get it compares a missing header as an empty string
+func PlainHeaderExpect(value string) HeaderExpect {
+	return HeaderExpect{Expect: Expect{Equals: value, Elements: ElementsFirst}, Plain: true}
+}
+
+func (h *HeaderExpect) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if errValue := unmarshal(&value); errValue == nil {
+		*h = PlainHeaderExpect(value)
+		return nil
+	}
+	return unmarshal(&h.Expect)
+}
+
+// MarshalYAML writes the plain string form back as a string
+func (h HeaderExpect) MarshalYAML() (interface{}, error) {
+	if value, ok := h.Equals.(string); h.Plain && ok {
+		return value, nil
+	}
+	return h.Expect.MarshalYAML()
+}
+
+// JSONSchema validates the response body or a sub document against a JSON schema
+type JSONSchema struct {
+	// inline schema
+	Schema interface{} `yaml:"schema"`
+	// schema file relative to the config dir - it is loaded together with the service
+	File string `yaml:"file"`
+	// optional json path selecting the sub document to validate
+	JSONPath string `yaml:"jsonPath"`
 }
 
 type Check struct {
-	Comment     string            `yaml:"comment"`
-	JSONPath    map[string]Expect `yaml:"jsonPath"`
-	GoQuery     map[string]Expect `yaml:"goQuery"`
-	Headers     map[string]string `yaml:"headers"`
-	Regex       map[string]Expect `yaml:"regex"`
-	Duration    time.Duration     `yaml:"duration"`
-	StatusCode  int64             `yaml:"statusCode"`
-	ContentType string            `yaml:"contentType"`
-	Redirect    string            `yaml:"redirect"`
-	MatchReply  string            `yaml:"matchReply"`
+	Comment     string                  `yaml:"comment"`
+	JSONPath    map[string]Expect       `yaml:"jsonPath"`
+	JSONSchema  *JSONSchema             `yaml:"jsonSchema"`
+	GoQuery     map[string]Expect       `yaml:"goQuery"`
+	XPath       map[string]Expect       `yaml:"xPath"`
+	Headers     map[string]HeaderExpect `yaml:"headers"`
+	Regex       map[string]Expect       `yaml:"regex"`
+	Duration    time.Duration           `yaml:"duration"`
+	StatusCode  int64                   `yaml:"statusCode"`
+	ContentType string                  `yaml:"contentType"`
+	Redirect    string                  `yaml:"redirect"`
+	MatchReply  string                  `yaml:"matchReply"`
+	// every response of a followed redirect chain including the final one
+	RedirectChain []RedirectHop `yaml:"redirectChain"`
+}
+
+// RedirectHop describes a response of a redirect chain - unset fields are not checked
+type RedirectHop struct {
+	StatusCode int64 `yaml:"statusCode"`
+	// the requested URL
+	URL string `yaml:"url"`
+}
+
+// Extract captures a value of the response into a session variable - set exactly one source
+type Extract struct {
+	// first result of a json path, strings are unquoted, other values are raw JSON
+	JSONPath string `yaml:"jsonPath"`
+	// first element of a css selector, optionally with @attribute, @html() or @text()
+	GoQuery string `yaml:"goQuery"`
+	// first match of a regular expression or its first capturing group
+	Regex string `yaml:"regex"`
+	// response header
+	Header string `yaml:"header"`
 }
 
 type Call struct {
 	// allow to overwrite scheme
-	Scheme      string            `yaml:"scheme"`
+	Scheme string `yaml:"scheme"`
+	// allow to overwrite host and port of the endpoint, e.g. for an auth server - uri may be an absolute URL, too
+	Host        string            `yaml:"host"`
 	URI         string            `yaml:"uri"`
 	URL         string            `yaml:"url"`
 	Method      string            `yaml:"method"`
@@ -44,6 +207,42 @@ type Call struct {
 	Check       []Check           `yaml:"check"`
 	Headers     map[string]string `yaml:"headers"`
 	Comment     string            `yaml:"comment"`
+	// variables for the following calls of the session, use them like {{ .vars.name }} in uri, headers and data
+	Extract map[string]Extract `yaml:"extract"`
+	// raw request body from a file relative to the config dir
+	DataFile string `yaml:"dataFile"`
+	// multipart/form-data file parts: field name -> file relative to the config dir
+	Files map[string]string `yaml:"files"`
+	// contents of dataFile and files - they are loaded together with the service
+	FileContents map[string][]byte `yaml:"-"`
+	// maximum duration of the request including reading the response, defaults to 30s
+	Timeout time.Duration `yaml:"timeout"`
+	// repeat the call for each value, the value is the variable {{ .vars.item }} or the one named by as
+	ForEach []string `yaml:"forEach"`
+	As      string   `yaml:"as"`
+	// follow redirects and check the final response, at most maxRedirects hops - defaults to 10
+	FollowRedirects bool `yaml:"followRedirects"`
+	MaxRedirects    int  `yaml:"maxRedirects"`
+}
+
+const defaultForEachVar = "item"
+
+// ForEachVar is the name of the variable, that holds the current value of forEach
+func (c Call) ForEachVar() string {
+	if c.As != "" {
+		return c.As
+	}
+	return defaultForEachVar
+}
+
+// OpenAPI validates every call of a session against an OpenAPI 3 document
+type OpenAPI struct {
+	// OpenAPI 3 document relative to the config dir - it is loaded together with the service
+	File string `yaml:"file"`
+	// prefix of the call paths, that is not part of the documented paths - defaults to the path of the first server
+	BasePath string `yaml:"basePath"`
+	// content of the file
+	Document []byte `yaml:"-"`
 }
 
 // Service is a service to monitor
@@ -58,24 +257,53 @@ type Service struct {
 	Endpoint string        `yaml:"endpoint"`
 	Interval time.Duration `yaml:"interval"`
 
-	Session    []Call        `yaml:"session"`
+	Session []Call `yaml:"session"`
+
+	// maximum duration of the whole session, by default only the calls have a timeout
+	SessionTimeout time.Duration `yaml:"sessionTimeout"`
+
+	// validate responses against an OpenAPI document
+	OpenAPI *OpenAPI `yaml:"openAPI"`
 
 	// Notifications
 	NotifyIfResolved bool `yaml:"notifyIfResolved"`
 
 	// Generate an error if the TLS certificate will expire in less then
 	TLSWarning time.Duration `yaml:"tlsWarning"`
+
+	// headers for every call, headers of a call take precedence
+	Headers map[string]string `yaml:"headers"`
+
+	// reported with every result
+	Labels map[string]string `yaml:"labels"`
+
+	// checks for every call, they follow the checks of the call
+	Check []Check `yaml:"check"`
+}
+
+// Defaults are read from _defaults.yml for all services in its folder and below - deeper folders and the services override them
+type Defaults struct {
+	Interval         time.Duration     `yaml:"interval"`
+	TLSWarning       time.Duration     `yaml:"tlsWarning"`
+	NotifyIfResolved bool              `yaml:"notifyIfResolved"`
+	Headers          map[string]string `yaml:"headers"`
+	Labels           map[string]string `yaml:"labels"`
+	Check            []Check           `yaml:"check"`
 }
 
 // Server models the petze.yml main config file
 type Server struct {
 
 	// endpoint to expose metrics
-	Address       string `yaml:"address"`
+	Address string `yaml:"address"`
 
 	// auth
 	BasicAuthFile string `yaml:"basicAuthFile"`
 
+	// limits of concurrently running sessions, in total and per host of the service endpoints
+	MaxSessions        int `yaml:"maxSessions"`
+	MaxSessionsPerHost int `yaml:"maxSessionsPerHost"`
+
 	// Notifications
 	TLS *struct {
 		Address string `yaml:"address"`
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalHeaderExpect(t *testing.T) {
+	chk := Check{}
+	errUnmarshal := yaml.UnmarshalStrict([]byte(`
+headers:
+  X-Test: foo
+  X-Request-Id:
+    matches: "^req-"
+  Server:
+    absent: true
+`), &chk)
+	if errUnmarshal != nil {
+		t.Fatal(errUnmarshal)
+	}
+	if chk.Headers["X-Test"].Equals != "foo" || chk.Headers["X-Test"].Elements != ElementsFirst || !chk.Headers["X-Test"].Plain {
+		t.Error("a plain string has to expect the first value to be equal", chk.Headers["X-Test"])
+	}
+	if chk.Headers["X-Request-Id"].Matches != "^req-" || chk.Headers["X-Request-Id"].Plain || !chk.Headers["Server"].Absent {
+		t.Error("unexpected header expectations", chk.Headers)
+	}
+
+	if errTypo := yaml.UnmarshalStrict([]byte("headers:\n  Server:\n    absnet: true\n"), &Check{}); errTypo == nil {
+		t.Error("expected an error for an unknown operator")
+	}
+}
+
+func TestUnmarshalExpectNull(t *testing.T) {
+	expect := Expect{}
+	if errUnmarshal := yaml.UnmarshalStrict([]byte("equals: null\n"), &expect); errUnmarshal != nil {
+		t.Fatal(errUnmarshal)
+	}
+	if !expect.HasEquals() || expect.Equals != nil || expect.HasNotEquals() || !expect.HasValueConstraint() {
+		t.Error("an explicit null has to be an equals expectation", expect)
+	}
+	unset := Expect{}
+	if errUnmarshal := yaml.UnmarshalStrict([]byte("min: 1\n"), &unset); errUnmarshal != nil {
+		t.Fatal(errUnmarshal)
+	}
+	if unset.HasEquals() || unset.HasValueConstraint() {
+		t.Error("a missing equals must not be an expectation", unset)
+	}
+	if errTypo := yaml.UnmarshalStrict([]byte("equlas: null\n"), &Expect{}); errTypo == nil {
+		t.Error("expected an error for an unknown operator")
+	}
+}
+
+func TestMarshalHeaderExpect(t *testing.T) {
+	chk := Check{}
+	if errUnmarshal := yaml.UnmarshalStrict([]byte(`
+headers:
+  X-Test: foo
+  X-Request-Id:
+    matches: "^req-"
+  X-Null:
+    equals: null
+`), &chk); errUnmarshal != nil {
+		t.Fatal(errUnmarshal)
+	}
+	yamlBytes, errMarshal := yaml.Marshal(chk)
+	if errMarshal != nil {
+		t.Fatal(errMarshal)
+	}
+	loaded := Check{}
+	if errUnmarshal := yaml.UnmarshalStrict(yamlBytes, &loaded); errUnmarshal != nil {
+		t.Fatal(errUnmarshal, string(yamlBytes))
+	}
+	if !reflect.DeepEqual(chk.Headers, loaded.Headers) {
+		t.Error("headers have to load back", string(yamlBytes))
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// folderDefaults reads the _defaults.yml files of the folders once per load
+type folderDefaults struct {
+	configDir string
+	folders   map[string]*Defaults
+}
+
+func newFolderDefaults(configDir string) *folderDefaults {
+	return &folderDefaults{
+		configDir: configDir,
+		folders:   map[string]*Defaults{},
+	}
+}
+
+// get the defaults of a folder, they inherit the defaults of the parent folders up to the config dir
+func (f *folderDefaults) get(dir string) (*Defaults, error) {
+	if defaults, ok := f.folders[dir]; ok {
+		return defaults, nil
+	}
+	parent := &Defaults{}
+	if dir != f.configDir {
+		var errParent error
+		parent, errParent = f.get(filepath.Dir(dir))
+		if errParent != nil {
+			return nil, errParent
+		}
+	}
+	// the file is loaded on top of the parent defaults, strict yaml does not allow to load maps on top of each other
+	defaults := &Defaults{
+		Interval:         parent.Interval,
+		TLSWarning:       parent.TLSWarning,
+		NotifyIfResolved: parent.NotifyIfResolved,
+		Check:            parent.Check,
+	}
+	defaultsFile := filepath.Join(dir, DefaultsConfigFile)
+	if _, errStat := os.Stat(defaultsFile); errStat == nil {
+		if errLoad := load(f.configDir, defaultsFile, defaults); errLoad != nil {
+			return nil, errors.New("invalid defaults " + defaultsFile + " : " + errLoad.Error())
+		}
+	}
+	defaults.Headers = mergeStrings(parent.Headers, defaults.Headers)
+	defaults.Labels = mergeStrings(parent.Labels, defaults.Labels)
+	f.folders[dir] = defaults
+	return defaults, nil
+}
+
+// service creates a service with the defaults, the service file has to be loaded on top of it, before the maps are merged
+func (d *Defaults) service() *Service {
+	return &Service{
+		Interval:         d.Interval,
+		TLSWarning:       d.TLSWarning,
+		NotifyIfResolved: d.NotifyIfResolved,
+		Check:            append([]Check(nil), d.Check...),
+	}
+}
+
+// mergeDefaults adds the default headers and labels, that the service did not set
+func (d *Defaults) mergeDefaults(s *Service) {
+	s.Headers = mergeStrings(d.Headers, s.Headers)
+	s.Labels = mergeStrings(d.Labels, s.Labels)
+}
+
+// applyToCalls adds the headers and checks of the service to all calls
+func (s *Service) applyToCalls() {
+	for i, call := range s.Session {
+		if len(s.Headers) > 0 {
+			s.Session[i].Headers = mergeStrings(s.Headers, call.Headers)
+		}
+		if len(s.Check) > 0 {
+			s.Session[i].Check = append(append([]Check(nil), call.Check...), s.Check...)
+		}
+	}
+}
+
+// mergeStrings returns a new map, values of override take precedence
+func mergeStrings(base, override map[string]string) map[string]string {
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadServicesDefaults(t *testing.T) {
+	dir, errDir := ioutil.TempDir("", "petze-defaults")
+	if errDir != nil {
+		t.Fatal(errDir)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "cluster"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, DefaultsConfigFile), []byte(`
+interval: 5m
+notifyIfResolved: true
+headers:
+  X-A: root
+  X-B: root
+labels:
+  team: a
+check:
+  - statusCode: 200
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "cluster", DefaultsConfigFile), []byte(`
+interval: 10m
+headers:
+  X-B: cluster
+labels:
+  env: prod
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "cluster", "service.yml"), []byte(`
+endpoint: http://example.com
+notifyIfResolved: false
+session:
+  - uri: /
+    headers:
+      X-A: call
+    check:
+      - contentType: text/html
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "top.yml"), []byte(`
+endpoint: http://example.com
+session:
+  - uri: /
+`), 0644)
+
+	services, errLoad := LoadServices(dir)
+	if errLoad != nil {
+		t.Fatal(errLoad)
+	}
+	if len(services) != 2 {
+		t.Fatal("defaults must not be loaded as services", services)
+	}
+
+	top := services["top"]
+	if top.Interval != 5*time.Minute || !top.NotifyIfResolved {
+		t.Error("unexpected defaults", top.Interval, top.NotifyIfResolved)
+	}
+	if !reflect.DeepEqual(top.Session[0].Headers, map[string]string{"X-A": "root", "X-B": "root"}) {
+		t.Error("unexpected headers", top.Session[0].Headers)
+	}
+
+	service := services["cluster/service"]
+	if service.Interval != 10*time.Minute || service.NotifyIfResolved {
+		t.Error("deeper folders and services have to override defaults", service.Interval, service.NotifyIfResolved)
+	}
+	if !reflect.DeepEqual(service.Labels, map[string]string{"team": "a", "env": "prod"}) {
+		t.Error("unexpected labels", service.Labels)
+	}
+	call := service.Session[0]
+	if !reflect.DeepEqual(call.Headers, map[string]string{"X-A": "call", "X-B": "cluster"}) {
+		t.Error("unexpected headers", call.Headers)
+	}
+	if len(call.Check) != 2 || call.Check[0].ContentType != "text/html" || call.Check[1].StatusCode != 200 {
+		t.Error("common checks have to follow the checks of the call", call.Check)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "cluster", DefaultsConfigFile), []byte("endpoint: http://example.com\n"), 0644)
+	if _, errLoad := LoadServices(dir); errLoad == nil {
+		t.Error("expected an error for a field, that can not be a default")
+	}
+}
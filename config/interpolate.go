@@ -0,0 +1,204 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	fileReferencePrefix  = "file:"
+	plainReferencePrefix = "plain:"
+	redacted             = "***"
+	// shorter secrets would garble every message
+	minSecretLength = 4
+)
+
+// scopes of the secret registry - each load replaces the secrets of its scope
+const (
+	secretScopeServer   = "server"
+	secretScopeServices = "services"
+)
+
+// ${ENV_VAR}, ${plain:ENV_VAR} or ${file:/run/secrets/x} - $${ escapes a literal ${
+var referencePattern = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// secret values are redacted from results, notifications and logs
+var secrets = struct {
+	sync.RWMutex
+	scopes  map[string]map[string]bool
+	loading map[string]bool
+}{scopes: map[string]map[string]bool{}}
+
+// loadMutex serializes loads, while they collect their secrets
+var loadMutex sync.Mutex
+
+// collectSecrets runs a load and replaces the secrets of scope with the ones it resolved
+func collectSecrets(scope string, load func() error) error {
+	loadMutex.Lock()
+	defer loadMutex.Unlock()
+	secrets.Lock()
+	secrets.loading = map[string]bool{}
+	secrets.Unlock()
+	err := load()
+	secrets.Lock()
+	defer secrets.Unlock()
+	if err != nil {
+		// the previous configuration stays in use
+		for value := range secrets.scopes[scope] {
+			secrets.loading[value] = true
+		}
+	}
+	secrets.scopes[scope] = secrets.loading
+	secrets.loading = nil
+	return err
+}
+
+// Redact replaces all secret values, that were interpolated into the configuration
+func Redact(s string) string {
+	secrets.RLock()
+	values := []string{}
+	for _, scope := range secrets.scopes {
+		for value := range scope {
+			values = append(values, value)
+		}
+	}
+	for value := range secrets.loading {
+		values = append(values, value)
+	}
+	secrets.RUnlock()
+	// longer secrets first, they might contain shorter ones
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	for _, value := range values {
+		s = strings.Replace(s, value, redacted, -1)
+	}
+	return s
+}
+
+// addSecret registers a value for redaction by the running load
+func addSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	secrets.Lock()
+	defer secrets.Unlock()
+	if secrets.loading != nil {
+		secrets.loading[value] = true
+	}
+}
+
+// isEscaped tells if a match of referencePattern is an escaped $${
+func isEscaped(s string, match []int) bool {
+	return strings.HasPrefix(s[match[0]:], "$$")
+}
+
+// interpolate resolves references in all strings of an unmarshalled yaml document
+func interpolate(configDir string, source interface{}) (target interface{}, err error) {
+	switch s := source.(type) {
+	case map[interface{}]interface{}:
+		t := make(map[interface{}]interface{}, len(s))
+		for key, value := range s {
+			t[key], err = interpolate(configDir, value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case []interface{}:
+		t := make([]interface{}, len(s))
+		for i, value := range s {
+			t[i], err = interpolate(configDir, value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case string:
+		return interpolateString(configDir, s)
+	default:
+		return source, nil
+	}
+}
+
+func interpolateString(configDir string, s string) (target interface{}, err error) {
+	matches := referencePattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+	// a value, that is a single reference, keeps its type e.g. port: ${SMTP_PORT}
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) && !isEscaped(s, matches[0]) {
+		value, errResolve := resolveReference(configDir, s[matches[0][2]:matches[0][3]])
+		if errResolve != nil {
+			return nil, errResolve
+		}
+		if typed, ok := canonicalScalar(value); ok {
+			return typed, nil
+		}
+		return value, nil
+	}
+	interpolated := ""
+	last := 0
+	for _, match := range matches {
+		if isEscaped(s, match) {
+			interpolated += s[last:match[0]] + s[match[0]+1:match[1]]
+			last = match[1]
+			continue
+		}
+		value, errResolve := resolveReference(configDir, s[match[2]:match[3]])
+		if errResolve != nil {
+			return nil, errResolve
+		}
+		interpolated += s[last:match[0]] + value
+		last = match[1]
+	}
+	return interpolated + s[last:], nil
+}
+
+// resolveReference resolves a reference - all values except the ones of plain: references are registered as secrets
+func resolveReference(configDir, reference string) (value string, err error) {
+	switch {
+	case strings.HasPrefix(reference, plainReferencePrefix):
+		// not a secret, like a host or a port
+		return lookupEnv(strings.TrimPrefix(reference, plainReferencePrefix))
+	case strings.HasPrefix(reference, fileReferencePrefix):
+		file := strings.TrimPrefix(reference, fileReferencePrefix)
+		valueBytes, errRead := ioutil.ReadFile(configFilePath(configDir, file))
+		if errRead != nil {
+			return "", errors.New("could not read secret file: " + errRead.Error())
+		}
+		value = strings.TrimRight(string(valueBytes), "\r\n")
+	default:
+		value, err = lookupEnv(reference)
+		if err != nil {
+			return "", err
+		}
+	}
+	addSecret(value)
+	return value, nil
+}
+
+func lookupEnv(name string) (value string, err error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.New("environment variable " + name + " is not set")
+	}
+	return value, nil
+}
+
+// canonicalScalar converts integers and booleans, that survive a round trip without changes
+func canonicalScalar(value string) (typed interface{}, ok bool) {
+	if i, errParse := strconv.ParseInt(value, 10, 64); errParse == nil && strconv.FormatInt(i, 10) == value {
+		return i, true
+	}
+	if b, errParse := strconv.ParseBool(value); errParse == nil && strconv.FormatBool(b) == value {
+		return b, true
+	}
+	return nil, false
+}
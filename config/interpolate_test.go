@@ -0,0 +1,114 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadInterpolated(t *testing.T) {
+	dir, errDir := ioutil.TempDir("", "petze-interpolate")
+	if errDir != nil {
+		t.Fatal(errDir)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("PETZE_TEST_SMTP_PASS", "env-s3cr3t")
+	os.Setenv("PETZE_TEST_SMTP_PORT", "587")
+	os.Setenv("PETZE_TEST_SMTP_USER", "petze-user")
+	defer os.Unsetenv("PETZE_TEST_SMTP_PASS")
+	defer os.Unsetenv("PETZE_TEST_SMTP_PORT")
+	defer os.Unsetenv("PETZE_TEST_SMTP_USER")
+	ioutil.WriteFile(filepath.Join(dir, "token"), []byte("file-s3cr3t\n"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, ServerConfigFile), []byte(`
+smtp:
+  user: ${plain:PETZE_TEST_SMTP_USER}
+  pass: ${PETZE_TEST_SMTP_PASS}
+  port: ${plain:PETZE_TEST_SMTP_PORT}
+slack: "https://hooks.example.com/${file:token}"
+`), 0644)
+
+	server, errLoad := LoadServer(dir)
+	if errLoad != nil {
+		t.Fatal(errLoad)
+	}
+	if server.SMTP.User != "petze-user" || server.SMTP.Pass != "env-s3cr3t" || server.SMTP.Port != 587 || server.Slack != "https://hooks.example.com/file-s3cr3t" {
+		t.Error("unexpected interpolation", server.SMTP, server.Slack)
+	}
+	if redactedURL := Redact("Post https://hooks.example.com/file-s3cr3t: env-s3cr3t"); redactedURL != "Post https://hooks.example.com/***: ***" {
+		t.Error("secrets were not redacted", redactedURL)
+	}
+	if message := Redact("petze-user on port 587"); message != "petze-user on port 587" {
+		t.Error("plain references must not be redacted", message)
+	}
+
+	// a reload replaces the secrets
+	ioutil.WriteFile(filepath.Join(dir, ServerConfigFile), []byte(`slack: https://hooks.example.com/plain`), 0644)
+	if _, errLoad := LoadServer(dir); errLoad != nil {
+		t.Fatal(errLoad)
+	}
+	if message := Redact("env-s3cr3t"); message != "env-s3cr3t" {
+		t.Error("secrets of the previous configuration were not removed", message)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, ServerConfigFile), []byte(`slack: ${PETZE_TEST_MISSING}`), 0644)
+	if _, errLoad := LoadServer(dir); errLoad == nil {
+		t.Error("expected an error for a missing environment variable")
+	}
+}
+
+func TestInterpolateString(t *testing.T) {
+	os.Setenv("PETZE_TEST_HOST", "example.com")
+	defer os.Unsetenv("PETZE_TEST_HOST")
+	tests := []struct {
+		in  string
+		out interface{}
+	}{
+		{"https://${PETZE_TEST_HOST}/", "https://example.com/"},
+		{"$${PETZE_TEST_HOST}", "${PETZE_TEST_HOST}"},
+		{"echo $${HOME} on ${PETZE_TEST_HOST}", "echo ${HOME} on example.com"},
+		{"${", "${"},
+	}
+	for _, test := range tests {
+		out, err := interpolateString("", test.in)
+		if err != nil || out != test.out {
+			t.Error("unexpected interpolation of", test.in, out, err)
+		}
+	}
+}
+
+func TestAddSecretMinLength(t *testing.T) {
+	defer collectSecrets(secretScopeServices, func() error { return nil })
+	errCollect := collectSecrets(secretScopeServices, func() error {
+		addSecret("80")
+		addSecret("s3cr3t")
+		return nil
+	})
+	if errCollect != nil {
+		t.Fatal(errCollect)
+	}
+	if message := Redact("port 80 s3cr3t"); message != "port 80 ***" {
+		t.Error("unexpected redaction", message)
+	}
+}
+
+func TestLoadInterpolatedDuplicateKeys(t *testing.T) {
+	dir, errDir := ioutil.TempDir("", "petze-interpolate")
+	if errDir != nil {
+		t.Fatal(errDir)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("PETZE_TEST_HOST", "example.com")
+	defer os.Unsetenv("PETZE_TEST_HOST")
+
+	ioutil.WriteFile(filepath.Join(dir, "service.yml"), []byte("endpoint: https://${plain:PETZE_TEST_HOST}\nendpoint: https://other.example.com\n"), 0644)
+	if _, errLoad := LoadServices(dir); errLoad == nil {
+		t.Error("expected an error for a duplicate key in a file with references")
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "service.yml"), []byte("targets:\n  de: {}\ntemplate:\n  endpoint: https://${target.name}.example.com\n  interval: 1m\n  interval: 2m\n"), 0644)
+	if _, errLoad := LoadServices(dir); errLoad == nil {
+		t.Error("expected an error for a duplicate key in a template")
+	}
+}
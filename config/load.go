@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"os"
 	"path"
 	"path/filepath"
@@ -17,9 +18,13 @@ import (
 // warn one week before the cert will expire by default
 const defaultTLSExpiryWarning = 7 * 24 * time.Hour
 
+const defaultInterval = time.Minute
+
 func LoadServices(configDir string) (services map[string]*Service, err error) {
 	services = make(map[string]*Service)
-	errLoadServices := loadServicesFromDir(configDir, services)
+	errLoadServices := collectSecrets(secretScopeServices, func() error {
+		return loadServicesFromDir(configDir, services)
+	})
 	if errLoadServices != nil {
 		err = errors.New("could not load service configurations from config dir : " + configDir + ",  : " + errLoadServices.Error())
 		return
@@ -27,7 +32,7 @@ func LoadServices(configDir string) (services map[string]*Service, err error) {
 	for id, service := range services {
 		service.ID = id
 		if service.Interval == 0 {
-			service.Interval = 60
+			service.Interval = defaultInterval
 		}
 	}
 	return services, nil
@@ -35,7 +40,9 @@ func LoadServices(configDir string) (services map[string]*Service, err error) {
 
 func LoadServer(configDir string) (server *Server, err error) {
 	server = &Server{}
-	return server, load(path.Join(configDir, serverConfigFile), &server)
+	return server, collectSecrets(secretScopeServer, func() error {
+		return load(configDir, path.Join(configDir, ServerConfigFile), &server)
+	})
 }
 
 func loadServicesFromDir(configDir string, targets map[string]*Service) error {
@@ -43,43 +50,229 @@ func loadServicesFromDir(configDir string, targets map[string]*Service) error {
 	if errAbsoluteConfigDir != nil {
 		return errAbsoluteConfigDir
 	}
+	defaults := newFolderDefaults(absoluteConfigDir)
 	return filepath.Walk(absoluteConfigDir, func(fp string, info os.FileInfo, err error) error {
-		if !info.IsDir() && !strings.HasPrefix(info.Name(), ".") && strings.HasSuffix(fp, ".yml") && info.Name() != "petze.yml" {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !strings.HasPrefix(info.Name(), ".") && strings.HasSuffix(fp, ".yml") && info.Name() != ServerConfigFile && info.Name() != DefaultsConfigFile {
 			p := strings.TrimSuffix(strings.TrimPrefix(fp, absoluteConfigDir+string(os.PathSeparator)), ".yml")
-			serviceConfig := &Service{}
+			folderDefaults, errDefaults := defaults.get(filepath.Dir(fp))
+			if errDefaults != nil {
+				return errDefaults
+			}
+			template, errTemplate := readServiceTemplate(fp)
+			if errTemplate != nil {
+				return errTemplate
+			}
+			if template != nil {
+				return template.expand(absoluteConfigDir, fp, p, folderDefaults, targets)
+			}
+			if _, ok := targets[p]; ok {
+				return errors.New("duplicate service id " + p + " in " + fp)
+			}
+			serviceConfig := folderDefaults.service()
 			targets[p] = serviceConfig
-			loadErr := load(fp, &serviceConfig)
+			loadErr := load(absoluteConfigDir, fp, &serviceConfig)
 			if loadErr != nil {
 				return loadErr
 			}
-			for i, call := range serviceConfig.Session {
-				if call.Data != nil {
-					serviceConfig.Session[i].Data = fixYamlMapsForJSON(call.Data, 0)
-				}
-			}
-			if serviceConfig.TLSWarning == 0 {
-				serviceConfig.TLSWarning = defaultTLSExpiryWarning
-			}
-			return nil
+			return prepareService(absoluteConfigDir, fp, folderDefaults, serviceConfig)
 		}
 		return nil
 	})
 }
 
-// Load load config from a file
-func load(configFile string, target interface{}) error {
+// prepareService applies the folder defaults to a loaded service and loads the files it references
+func prepareService(configDir, fp string, folderDefaults *Defaults, serviceConfig *Service) error {
+	folderDefaults.mergeDefaults(serviceConfig)
+	serviceConfig.applyToCalls()
+	for i, call := range serviceConfig.Session {
+		if call.Data != nil {
+			serviceConfig.Session[i].Data = fixYamlMapsForJSON(call.Data, 0)
+		}
+		if errBody := loadCallBody(configDir, &serviceConfig.Session[i]); errBody != nil {
+			return errors.New(fmt.Sprint("invalid request body of call ", i, " in ", fp, " : ", errBody.Error()))
+		}
+		if strings.ContainsAny(call.Host, "/?#") {
+			return errors.New(fmt.Sprint("invalid host ", call.Host, " in call ", i, " in ", fp, " : only host and port are allowed"))
+		}
+		if call.As != "" && len(call.ForEach) == 0 {
+			return errors.New(fmt.Sprint("as requires forEach in call ", i, " in ", fp))
+		}
+		for name, extract := range call.Extract {
+			if errExtract := validateExtract(extract); errExtract != nil {
+				return errors.New("invalid extract " + name + " in " + fp + " : " + errExtract.Error())
+			}
+		}
+		for _, chk := range call.Check {
+			if len(chk.RedirectChain) > 0 && !call.FollowRedirects {
+				return errors.New(fmt.Sprint("redirectChain requires followRedirects in call ", i, " in ", fp))
+			}
+			if chk.JSONSchema != nil {
+				errSchema := loadJSONSchema(configDir, chk.JSONSchema)
+				if errSchema != nil {
+					return errors.New("invalid jsonSchema in " + fp + " : " + errSchema.Error())
+				}
+			}
+		}
+	}
+	if serviceConfig.OpenAPI != nil {
+		errOpenAPI := loadOpenAPI(configDir, serviceConfig.OpenAPI)
+		if errOpenAPI != nil {
+			return errors.New("invalid openAPI in " + fp + " : " + errOpenAPI.Error())
+		}
+	}
+	if serviceConfig.TLSWarning == 0 {
+		serviceConfig.TLSWarning = defaultTLSExpiryWarning
+	}
+	return nil
+}
+
+func validateExtract(extract Extract) error {
+	sources := 0
+	for _, source := range []string{extract.JSONPath, extract.GoQuery, extract.Regex, extract.Header} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return errors.New("exactly one of jsonPath, goQuery, regex or header is required")
+	}
+	return nil
+}
+
+// loadCallBody reads the body and multipart files of a call relative to the config dir
+func loadCallBody(configDir string, call *Call) error {
+	mediaType := ""
+	if call.ContentType != "" {
+		parsedMediaType, _, errParse := mime.ParseMediaType(call.ContentType)
+		if errParse != nil {
+			return errors.New("invalid contentType " + call.ContentType + " : " + errParse.Error())
+		}
+		mediaType = parsedMediaType
+	}
+	switch {
+	case call.DataFile != "" && call.Data != nil:
+		return errors.New("use either data or dataFile")
+	case len(call.Files) > 0 && mediaType != ContentTypeMultipart:
+		return errors.New("files require contentType " + ContentTypeMultipart)
+	case call.DataFile != "" && (mediaType == ContentTypeForm || mediaType == ContentTypeMultipart):
+		return errors.New("dataFile is sent as it is, it can not be used with contentType " + mediaType)
+	}
+	files := []string{}
+	if call.DataFile != "" {
+		files = append(files, call.DataFile)
+	}
+	for _, file := range call.Files {
+		files = append(files, file)
+	}
+	for _, file := range files {
+		content, errRead := ioutil.ReadFile(configFilePath(configDir, file))
+		if errRead != nil {
+			return errRead
+		}
+		if call.FileContents == nil {
+			call.FileContents = map[string][]byte{}
+		}
+		call.FileContents[file] = content
+	}
+	return nil
+}
+
+// loadOpenAPI loads an OpenAPI document relative to the config dir
+func loadOpenAPI(configDir string, openAPI *OpenAPI) error {
+	if openAPI.File == "" {
+		return errors.New("file is required")
+	}
+	document, errRead := ioutil.ReadFile(configFilePath(configDir, openAPI.File))
+	if errRead != nil {
+		return errRead
+	}
+	openAPI.Document = document
+	return nil
+}
+
+// configFilePath resolves paths relative to the config dir
+func configFilePath(configDir, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(configDir, file)
+}
+
+// loadJSONSchema loads a schema file relative to the config dir and prepares inline schemas for JSON
+func loadJSONSchema(configDir string, schema *JSONSchema) error {
+	switch {
+	case schema.Schema != nil && schema.File != "":
+		return errors.New("use either schema or file")
+	case schema.File != "":
+		schemaFile := configFilePath(configDir, schema.File)
+		schemaBytes, errRead := ioutil.ReadFile(schemaFile)
+		if errRead != nil {
+			return errRead
+		}
+		// yaml is a superset of JSON
+		var fileSchema interface{}
+		errUnmarshal := yaml.Unmarshal(schemaBytes, &fileSchema)
+		if errUnmarshal != nil {
+			return errors.New("could not unmarshal schema file " + schemaFile + " : " + errUnmarshal.Error())
+		}
+		if fileSchema == nil {
+			return errors.New("schema file " + schemaFile + " is empty")
+		}
+		schema.Schema = fixYamlMapsForJSON(fileSchema, 0)
+	case schema.Schema != nil:
+		schema.Schema = fixYamlMapsForJSON(schema.Schema, 0)
+	default:
+		return errors.New("schema or file is required")
+	}
+	return nil
+}
+
+// Load load config from a file and resolve ${ENV_VAR}, ${plain:ENV_VAR} and ${file:path} references - files are relative to the config dir
+func load(configDir, configFile string, target interface{}) error {
 	configBytes, err := ioutil.ReadFile(configFile)
 	if err != nil {
 		return err
 	}
+	if strings.Contains(string(configBytes), "${") {
+		// strict like unmarshal - the documents are marshalled again, duplicate keys would get lost
+		var document interface{}
+		yamlErr := yaml.UnmarshalStrict(configBytes, &document)
+		if yamlErr != nil {
+			return errors.New("could not unmarshal yaml file " + configFile + " : " + yamlErr.Error())
+		}
+		return loadDocument(configDir, configFile, document, target)
+	}
+	return unmarshal(configFile, configBytes, target)
+}
+
+// loadDocument resolves the references of an unmarshalled yaml document and loads it into target
+func loadDocument(configDir, configFile string, document interface{}, target interface{}) error {
+	interpolated, errInterpolate := interpolate(configDir, document)
+	if errInterpolate != nil {
+		return errors.New("could not interpolate yaml file " + configFile + " : " + errInterpolate.Error())
+	}
+	configBytes, err := yaml.Marshal(interpolated)
+	if err != nil {
+		return err
+	}
+	return unmarshal(configFile, configBytes, target)
+}
+
+func unmarshal(configFile string, configBytes []byte, target interface{}) error {
 	yamlErr := yaml.UnmarshalStrict(configBytes, target)
 	if yamlErr != nil {
-		return errors.New("could not unmarshal yaml file " + configFile + " : " + yamlErr.Error())
+		return errors.New("could not unmarshal yaml file " + configFile + " : " + Redact(yamlErr.Error()))
 	}
 	return nil
 }
 
 func fixYamlMapsForJSON(source interface{}, level int) (target interface{}) {
+	if source == nil {
+		return nil
+	}
 	refl := reflect.ValueOf(source)
 	switch refl.Type().String() {
 	case "map[interface {}]interface {}":
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const targetReferencePrefix = "target."
+
+// serviceTemplate expands into one service per target - ${target.name} and ${target.<key>} are replaced in the template
+// e.g. shops.yml with the targets de and fr -> service IDs: shops/de, shops/fr
+type serviceTemplate struct {
+	Targets  map[string]map[string]string `yaml:"targets"`
+	Template interface{}                  `yaml:"template"`
+}
+
+// readServiceTemplate returns nil, if the service file is not a template
+func readServiceTemplate(configFile string) (template *serviceTemplate, err error) {
+	configBytes, errRead := ioutil.ReadFile(configFile)
+	if errRead != nil {
+		return nil, errRead
+	}
+	document := map[string]interface{}{}
+	if errUnmarshal := yaml.UnmarshalStrict(configBytes, &document); errUnmarshal != nil {
+		return nil, errors.New("could not unmarshal yaml file " + configFile + " : " + errUnmarshal.Error())
+	}
+	if _, ok := document["targets"]; !ok {
+		return nil, nil
+	}
+	template = &serviceTemplate{}
+	if errUnmarshal := yaml.UnmarshalStrict(configBytes, template); errUnmarshal != nil {
+		return nil, errors.New("could not unmarshal template " + configFile + " : " + errUnmarshal.Error())
+	}
+	if len(template.Targets) == 0 || template.Template == nil {
+		return nil, errors.New("template " + configFile + " needs targets and a template")
+	}
+	return template, nil
+}
+
+// expand the template into services with the ids <id>/<target name>
+func (t *serviceTemplate) expand(configDir, configFile, id string, folderDefaults *Defaults, targets map[string]*Service) error {
+	names := make([]string, 0, len(t.Targets))
+	for name := range t.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "" || strings.Contains(name, "/") {
+			return errors.New("invalid target name " + name + " in " + configFile)
+		}
+		serviceID := id + "/" + name
+		if _, ok := targets[serviceID]; ok {
+			return errors.New("duplicate service id " + serviceID + " in " + configFile)
+		}
+		document, errExpand := expandTarget(t.Template, name, t.Targets[name])
+		if errExpand != nil {
+			return errors.New("could not expand target " + name + " in " + configFile + " : " + errExpand.Error())
+		}
+		serviceConfig := folderDefaults.service()
+		targets[serviceID] = serviceConfig
+		if errLoad := loadDocument(configDir, configFile+"#"+name, document, &serviceConfig); errLoad != nil {
+			return errLoad
+		}
+		if errPrepare := prepareService(configDir, configFile+"#"+name, folderDefaults, serviceConfig); errPrepare != nil {
+			return errPrepare
+		}
+	}
+	return nil
+}
+
+// expandTarget replaces the target references in all strings of the template, other references are resolved later
+func expandTarget(source interface{}, name string, values map[string]string) (target interface{}, err error) {
+	switch s := source.(type) {
+	case map[interface{}]interface{}:
+		t := make(map[interface{}]interface{}, len(s))
+		for key, value := range s {
+			t[key], err = expandTarget(value, name, values)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case []interface{}:
+		t := make([]interface{}, len(s))
+		for i, value := range s {
+			t[i], err = expandTarget(value, name, values)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case string:
+		return expandTargetString(s, name, values)
+	default:
+		return source, nil
+	}
+}
+
+func expandTargetString(s string, name string, values map[string]string) (target interface{}, err error) {
+	matches := referencePattern.FindAllStringSubmatchIndex(s, -1)
+	expanded := ""
+	last := 0
+	for _, match := range matches {
+		reference := s[match[2]:match[3]]
+		if isEscaped(s, match) || !strings.HasPrefix(reference, targetReferencePrefix) {
+			continue
+		}
+		value, errValue := targetValue(strings.TrimPrefix(reference, targetReferencePrefix), name, values)
+		if errValue != nil {
+			return nil, errValue
+		}
+		// a value, that is a single reference, keeps its type like interpolated values
+		if match[0] == 0 && match[1] == len(s) {
+			if typed, ok := canonicalScalar(value); ok {
+				return typed, nil
+			}
+			return value, nil
+		}
+		expanded += s[last:match[0]] + value
+		last = match[1]
+	}
+	return expanded + s[last:], nil
+}
+
+func targetValue(key string, name string, values map[string]string) (value string, err error) {
+	if key == "name" {
+		return name, nil
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", errors.New("target " + name + " has no value " + key)
+	}
+	return value, nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadServicesTemplate(t *testing.T) {
+	dir, errDir := ioutil.TempDir("", "petze-template")
+	if errDir != nil {
+		t.Fatal(errDir)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, DefaultsConfigFile), []byte("interval: 5m\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "shops.yml"), []byte(`
+targets:
+  de:
+    domain: www.shop.de
+    locale: de-DE
+    status: 200
+  fr:
+    domain: www.shop.fr
+    locale: fr-FR
+    status: 301
+template:
+  endpoint: https://${target.domain}
+  session:
+    - uri: /${target.locale}/
+      comment: home of ${target.name}, not $${target.name}
+      check:
+        - statusCode: ${target.status}
+`), 0644)
+
+	services, errLoad := LoadServices(dir)
+	if errLoad != nil {
+		t.Fatal(errLoad)
+	}
+	if len(services) != 2 {
+		t.Fatal("expected a service per target", services)
+	}
+	fr, ok := services["shops/fr"]
+	if !ok {
+		t.Fatal("service ids have to be derived from the target names", services)
+	}
+	if fr.Endpoint != "https://www.shop.fr" || fr.Session[0].URI != "/fr-FR/" || fr.Session[0].Comment != "home of fr, not ${target.name}" {
+		t.Error("unexpected expansion", fr.Endpoint, fr.Session[0])
+	}
+	if fr.Session[0].Check[0].StatusCode != 301 {
+		t.Error("a single reference has to keep its type", fr.Session[0].Check[0])
+	}
+	if fr.Interval != 5*time.Minute {
+		t.Error("folder defaults have to apply to templates", fr.Interval)
+	}
+	if services["shops/de"].Endpoint != "https://www.shop.de" {
+		t.Error("unexpected expansion", services["shops/de"].Endpoint)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "shops.yml"), []byte(`
+targets:
+  de:
+    domain: www.shop.de
+template:
+  endpoint: https://${target.host}
+`), 0644)
+	if _, errLoad := LoadServices(dir); errLoad == nil {
+		t.Error("expected an error for a missing target value")
+	}
+}
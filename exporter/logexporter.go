@@ -10,7 +10,11 @@ func LogResultHandler(result watch.Result) {
 		"service_id": result.ID,
 		"runtime":    result.RunTime,
 		"timeout":    result.Timeout,
+		"lateness":   result.Lateness,
 	})
+	if len(result.Labels) > 0 {
+		logger = logger.WithField("labels", result.Labels)
+	}
 
 	if len(result.Errors) > 0 {
 		for _, err := range result.Errors {
@@ -16,15 +16,36 @@ var (
 		Name: "petze_service_session_execution_time",
 		Help: "Service response times per session execution",
 	}, []string{"service_id"})
+
+	serviceStartLateness = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "petze_service_start_lateness_seconds",
+		Help: "How late the last session of a service started after its scheduled time",
+	}, []string{"service_id"})
+
+	configError = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "petze_config_error",
+		Help: "1 while the service configuration can not be loaded and the previous one is still used",
+	})
 )
 
 func init() {
 	// Metrics have to be registered to be exposed:
 	prometheus.MustRegister(serviceErrors)
 	prometheus.MustRegister(serviceResponseTimes)
+	prometheus.MustRegister(serviceStartLateness)
+	prometheus.MustRegister(configError)
 }
 
 func PrometheusMetricsListener(result watch.Result) {
+	if result.ID == watch.ConfigID {
+		if len(result.Errors) > 0 {
+			configError.Set(1)
+		} else {
+			configError.Set(0)
+		}
+		return
+	}
 	serviceErrors.WithLabelValues(result.ID).Set(float64(len(result.Errors)))
 	serviceResponseTimes.WithLabelValues(result.ID).Set(float64(result.RunTime / time.Millisecond))
+	serviceStartLateness.WithLabelValues(result.ID).Set(result.Lateness.Seconds())
 }
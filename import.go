@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/foomo/petze/config"
+	"github.com/foomo/petze/importer"
+	log "github.com/sirupsen/logrus"
+)
+
+// runImport generates service configurations: petze import <type> <source> [flags]
+func runImport(args []string) {
+	if len(args) == 0 {
+		log.Fatal("please pass the import type: openapi or har")
+	}
+	switch args[0] {
+	case "openapi":
+		importOpenAPI(args[1:])
+	case "har":
+		importHAR(args[1:])
+	default:
+		log.Fatal("unknown import type: ", args[0])
+	}
+}
+
+func importOpenAPI(args []string) {
+	fs := flag.NewFlagSet("import openapi", flag.ExitOnError)
+	endpoint := fs.String("endpoint", "", "service endpoint, defaults to the first server of the document")
+	out := fs.String("out", "", "service configuration file to write, defaults to stdout")
+	positional := parseInterspersed(fs, args)
+	if len(positional) != 1 {
+		log.Fatal("usage: petze import openapi <spec> [--endpoint <url>] [--out <file>]")
+	}
+
+	document, errRead := ioutil.ReadFile(positional[0])
+	if errRead != nil {
+		log.Fatal(errRead)
+	}
+	service, errImport := importer.OpenAPI(document, *endpoint)
+	if errImport != nil {
+		log.Fatal(errImport)
+	}
+	writeService(service, *out)
+}
+
+func importHAR(args []string) {
+	fs := flag.NewFlagSet("import har", flag.ExitOnError)
+	options := importer.HAROptions{}
+	fs.BoolVar(&options.DropStatic, "drop-static", false, "drop images, stylesheets, scripts, fonts and media")
+	fs.BoolVar(&options.StripVolatileHeaders, "strip-volatile-headers", false, "strip cookies, caching validators and tracing headers")
+	stripHeaders := fs.String("strip-header", "", "comma separated list of additional headers to strip")
+	out := fs.String("out", "", "service configuration file to write, defaults to stdout")
+	positional := parseInterspersed(fs, args)
+	if len(positional) != 1 {
+		log.Fatal("usage: petze import har <file.har> [--drop-static] [--strip-volatile-headers] [--strip-header <names>] [--out <file>]")
+	}
+	if *stripHeaders != "" {
+		options.StripHeaders = strings.Split(*stripHeaders, ",")
+	}
+
+	harBytes, errRead := ioutil.ReadFile(positional[0])
+	if errRead != nil {
+		log.Fatal(errRead)
+	}
+	service, errImport := importer.HAR(harBytes, options)
+	if errImport != nil {
+		log.Fatal(errImport)
+	}
+	writeService(service, *out)
+}
+
+func writeService(service *config.Service, out string) {
+	configDir := ""
+	if out != "" {
+		configDir = configDirOf(out)
+		importer.MoveFiles(service, path.Join("files", serviceID(configDir, out)))
+		checkServiceFiles(service, configDir)
+	}
+	yamlBytes, errMarshal := importer.MarshalService(service)
+	if errMarshal != nil {
+		log.Fatal(errMarshal)
+	}
+	if out == "" {
+		os.Stdout.Write(yamlBytes)
+		for _, call := range service.Session {
+			if len(call.FileContents) > 0 {
+				log.Warn("recorded files are only written with --out")
+				break
+			}
+		}
+		return
+	}
+	if errWrite := ioutil.WriteFile(out, yamlBytes, 0644); errWrite != nil {
+		log.Fatal(errWrite)
+	}
+	writeServiceFiles(service, configDir)
+	log.Info("wrote ", len(service.Session), " calls to ", out)
+}
+
+// serviceID is the id petze will give the service file in the config folder
+func serviceID(configDir, serviceFile string) string {
+	absoluteFile, errAbs := filepath.Abs(serviceFile)
+	if errAbs != nil {
+		log.Fatal(errAbs)
+	}
+	rel, errRel := filepath.Rel(configDir, absoluteFile)
+	if errRel != nil {
+		log.Fatal(errRel)
+	}
+	return strings.TrimSuffix(filepath.ToSlash(rel), ".yml")
+}
+
+// checkServiceFiles refuses to overwrite files, other services might use them
+func checkServiceFiles(service *config.Service, dir string) {
+	for _, call := range service.Session {
+		for file := range call.FileContents {
+			fp := filepath.Join(dir, filepath.FromSlash(file))
+			if _, errStat := os.Stat(fp); errStat == nil {
+				log.Fatal("will not overwrite ", fp)
+			}
+		}
+	}
+}
+
+// configDirOf finds the config folder of a service file - the closest folder with a petze.yml, or the folder of the file
+func configDirOf(serviceFile string) string {
+	fileDir, errAbs := filepath.Abs(filepath.Dir(serviceFile))
+	if errAbs != nil {
+		return filepath.Dir(serviceFile)
+	}
+	for dir := fileDir; ; dir = filepath.Dir(dir) {
+		if _, errStat := os.Stat(filepath.Join(dir, config.ServerConfigFile)); errStat == nil {
+			return dir
+		}
+		if filepath.Dir(dir) == dir {
+			return fileDir
+		}
+	}
+}
+
+// writeServiceFiles writes the recorded files into the config folder, files are relative to it
+func writeServiceFiles(service *config.Service, dir string) {
+	for _, call := range service.Session {
+		for file, content := range call.FileContents {
+			fp := filepath.Join(dir, filepath.FromSlash(file))
+			if errMkdir := os.MkdirAll(filepath.Dir(fp), 0755); errMkdir != nil {
+				log.Fatal(errMkdir)
+			}
+			if errWrite := ioutil.WriteFile(fp, content, 0644); errWrite != nil {
+				log.Fatal(errWrite)
+			}
+			log.Info("wrote ", fp)
+		}
+	}
+}
+
+// parseInterspersed allows flags before and after positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) (positional []string) {
+	for {
+		// errors exit, see flag.ExitOnError
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,237 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/foomo/petze/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// HAROptions control which requests and headers of a recording are used
+type HAROptions struct {
+	// drop images, stylesheets, scripts, fonts and media
+	DropStatic bool
+	// strip headers like cookies, caching validators and tracing ids
+	StripVolatileHeaders bool
+	// additional headers to strip
+	StripHeaders []string
+}
+
+// headers managed by the http client are never copied
+var clientHeaders = []string{"host", "content-length", "connection", "accept-encoding", "transfer-encoding"}
+
+// headers, that change from recording to recording
+var volatileHeaders = []string{
+	"cookie", "if-none-match", "if-modified-since", "cache-control", "pragma", "referer",
+	"x-request-id", "x-correlation-id", "traceparent", "tracestate",
+}
+
+var staticResourceTypes = map[string]bool{"image": true, "stylesheet": true, "script": true, "font": true, "media": true}
+
+var staticExtensions = map[string]bool{
+	".css": true, ".js": true, ".mjs": true, ".map": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true, ".avif": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".mp4": true, ".webm": true, ".mp3": true,
+}
+
+type har struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	ResourceType string `json:"_resourceType"`
+	Request      struct {
+		Method   string      `json:"method"`
+		URL      string      `json:"url"`
+		Headers  []harHeader `json:"headers"`
+		PostData *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Params   []struct {
+				Name     string `json:"name"`
+				Value    string `json:"value"`
+				FileName string `json:"fileName"`
+			} `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int64       `json:"status"`
+		Headers []harHeader `json:"headers"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HAR generates a session from a browser HAR export with one call per request
+// the endpoint is taken from the first request, requests to other hosts are skipped
+func HAR(harBytes []byte, options HAROptions) (service *config.Service, err error) {
+	recording := &har{}
+	if errUnmarshal := json.Unmarshal(harBytes, recording); errUnmarshal != nil {
+		return nil, errors.New("could not parse HAR: " + errUnmarshal.Error())
+	}
+
+	stripped := map[string]bool{}
+	for _, name := range clientHeaders {
+		stripped[name] = true
+	}
+	if options.StripVolatileHeaders {
+		for _, name := range volatileHeaders {
+			stripped[name] = true
+		}
+	}
+	for _, name := range options.StripHeaders {
+		stripped[strings.ToLower(name)] = true
+	}
+
+	var endpointURL *url.URL
+	skippedHosts := map[string]int{}
+	for _, entry := range recording.Log.Entries {
+		requestURL, errURL := url.Parse(entry.Request.URL)
+		if errURL != nil || requestURL.Host == "" {
+			return nil, errors.New("invalid request url in HAR: " + entry.Request.URL)
+		}
+		// failed or blocked requests have no status
+		if entry.Response.Status == 0 || (options.DropStatic && isStatic(entry.ResourceType, requestURL, entry.Response.Content.MimeType)) {
+			continue
+		}
+		if endpointURL == nil {
+			endpointURL = requestURL
+			service = &config.Service{
+				Endpoint: requestURL.Scheme + "://" + requestURL.Host,
+				Interval: defaultInterval,
+			}
+		}
+		if requestURL.Host != endpointURL.Host {
+			skippedHosts[requestURL.Host]++
+			continue
+		}
+		service.Session = append(service.Session, harCall(entry, len(service.Session)+1, requestURL, endpointURL, stripped))
+	}
+	for host, count := range skippedHosts {
+		log.Warn("skipped ", count, " request(s) to ", host)
+	}
+	if service == nil {
+		return nil, errors.New("the HAR does not contain any usable requests")
+	}
+	return service, nil
+}
+
+// harCall converts the entry, that becomes the call with the given index
+func harCall(entry harEntry, index int, requestURL, endpointURL *url.URL, stripped map[string]bool) config.Call {
+	call := config.Call{
+		Method: entry.Request.Method,
+		URI:    requestURL.RequestURI(),
+	}
+	if call.Method == "GET" {
+		call.Method = ""
+	}
+	if requestURL.Scheme != endpointURL.Scheme {
+		call.Scheme = requestURL.Scheme
+	}
+	for _, header := range entry.Request.Headers {
+		name := strings.ToLower(header.Name)
+		// http/2 pseudo headers like :authority
+		if strings.HasPrefix(name, ":") || stripped[name] {
+			continue
+		}
+		// recorded as contentType, a multipart boundary has to match the body of the replay
+		if name == "content-type" && entry.Request.PostData != nil {
+			continue
+		}
+		if call.Headers == nil {
+			call.Headers = map[string]string{}
+		}
+		call.Headers[header.Name] = header.Value
+	}
+
+	if postData := entry.Request.PostData; postData != nil {
+		call.ContentType = postData.MimeType
+		mediaType, params, _ := mime.ParseMediaType(postData.MimeType)
+		if mediaType == config.ContentTypeMultipart {
+			// the boundary changes with every request, it is generated, when the session runs
+			call.ContentType = mediaType
+		}
+		fields := url.Values{}
+		for _, param := range postData.Params {
+			if param.FileName != "" {
+				addFile(&call, index, param.Name, param.FileName, []byte(param.Value))
+				continue
+			}
+			fields.Add(param.Name, param.Value)
+		}
+		switch {
+		case len(postData.Params) > 0:
+			if len(fields) > 0 {
+				call.Data = formData(fields)
+			}
+		case mediaType == config.ContentTypeMultipart:
+			if errMultipart := multipartData(&call, index, []byte(postData.Text), params["boundary"]); errMultipart != nil {
+				log.Warn("could not import multipart body of ", call.URI, ": ", errMultipart)
+			}
+		default:
+			call.Data = requestData(postData.MimeType, postData.Text, nil)
+		}
+	}
+
+	call.Check = append(call.Check, config.Check{StatusCode: entry.Response.Status})
+	contentType := ""
+	for _, header := range entry.Response.Headers {
+		if strings.ToLower(header.Name) == "content-type" {
+			contentType = header.Value
+		}
+	}
+	if contentType == "" {
+		contentType = entry.Response.Content.MimeType
+	}
+	if contentType != "" && entry.Response.Status != 204 && entry.Response.Status != 304 {
+		call.Check = append(call.Check, config.Check{ContentType: contentType})
+	}
+	return call
+}
+
+// requestData prefers parsed form parameters, then parsed JSON and falls back to the raw body
+func requestData(contentType, body string, form map[string]interface{}) interface{} {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case len(form) > 0:
+		return form
+	case mediaType == config.ContentTypeJSON || strings.HasSuffix(mediaType, "+json"):
+		var data interface{}
+		if json.Unmarshal([]byte(body), &data) == nil {
+			return data
+		}
+		return body
+	case body != "":
+		return body
+	}
+	return nil
+}
+
+// isStatic tells if a request loaded an image, a stylesheet, a script, a font or media
+func isStatic(resourceType string, requestURL *url.URL, contentType string) bool {
+	if staticResourceTypes[resourceType] {
+		return true
+	}
+	if staticExtensions[strings.ToLower(path.Ext(requestURL.Path))] {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasPrefix(mediaType, "image/") || strings.HasPrefix(mediaType, "font/") ||
+		strings.HasPrefix(mediaType, "video/") || strings.HasPrefix(mediaType, "audio/") ||
+		mediaType == "text/css" || strings.HasSuffix(mediaType, "javascript")
+}
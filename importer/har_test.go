@@ -0,0 +1,121 @@
+package importer
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/foomo/petze/config"
+	"gopkg.in/yaml.v2"
+)
+
+func loadTestHAR(t *testing.T, options HAROptions) *config.Service {
+	harBytes, errRead := ioutil.ReadFile("testdata/checkout.har")
+	if errRead != nil {
+		t.Fatal(errRead)
+	}
+	service, errImport := HAR(harBytes, options)
+	if errImport != nil {
+		t.Fatal(errImport)
+	}
+	yamlBytes, errMarshal := MarshalService(service)
+	if errMarshal != nil {
+		t.Fatal(errMarshal)
+	}
+	loaded := &config.Service{}
+	if errUnmarshal := yaml.UnmarshalStrict(yamlBytes, loaded); errUnmarshal != nil {
+		t.Fatal(errUnmarshal, string(yamlBytes))
+	}
+	return loaded
+}
+
+func TestHAR(t *testing.T) {
+	service := loadTestHAR(t, HAROptions{})
+	if service.Endpoint != "https://shop.example.com" {
+		t.Error("unexpected endpoint", service.Endpoint)
+	}
+	// other hosts and requests without response are skipped
+	if len(service.Session) != 5 {
+		t.Fatal("unexpected number of calls", len(service.Session))
+	}
+	login := service.Session[0]
+	if login.URI != "/login" || login.Headers["Cookie"] != "session=abc" || login.Headers[":authority"] != "" || login.Headers["Accept-Encoding"] != "" {
+		t.Error("unexpected login call", login)
+	}
+	if len(login.Check) != 2 || login.Check[0].StatusCode != 200 || login.Check[1].ContentType != "text/html; charset=utf-8" {
+		t.Error("unexpected login checks", login.Check)
+	}
+}
+
+func TestHAROptions(t *testing.T) {
+	service := loadTestHAR(t, HAROptions{DropStatic: true, StripVolatileHeaders: true, StripHeaders: []string{"Accept"}})
+	if len(service.Session) != 3 {
+		t.Fatal("static assets should be dropped", len(service.Session))
+	}
+	if len(service.Session[0].Headers) != 0 {
+		t.Error("headers should be stripped", service.Session[0].Headers)
+	}
+
+	form := service.Session[1]
+	data, _ := form.Data.(map[interface{}]interface{})
+	if form.Method != "POST" || form.ContentType != "application/x-www-form-urlencoded" || data["user"] != "petze" {
+		t.Error("unexpected form post", form)
+	}
+	if len(form.Check) != 1 || form.Check[0].StatusCode != 302 {
+		t.Error("unexpected form checks", form.Check)
+	}
+
+	api := service.Session[2]
+	data, _ = api.Data.(map[interface{}]interface{})
+	if api.URI != "/api/cart?x=1" || data["qty"] != 2 || api.Headers["X-Request-Id"] != "" {
+		t.Error("unexpected api call", api)
+	}
+}
+
+func TestHARMultipart(t *testing.T) {
+	harBytes := []byte(`{"log": {"entries": [
+	{
+		"request": {
+			"method": "POST",
+			"url": "https://shop.example.com/upload",
+			"headers": [{"name": "Content-Type", "value": "multipart/form-data; boundary=----recorded"}],
+			"postData": {
+				"mimeType": "multipart/form-data; boundary=----recorded",
+				"params": [
+					{"name": "tag", "value": "a"},
+					{"name": "tag", "value": "b"},
+					{"name": "upload", "fileName": "report.csv", "value": "a,b"}
+				]
+			}
+		},
+		"response": {"status": 200}
+	},
+	{
+		"request": {
+			"method": "POST",
+			"url": "https://shop.example.com/upload",
+			"postData": {
+				"mimeType": "multipart/form-data; boundary=xyz",
+				"text": "--xyz\r\nContent-Disposition: form-data; name=\"title\"\r\n\r\nreport\r\n--xyz--\r\n"
+			}
+		},
+		"response": {"status": 200}
+	}
+	]}}`)
+	service, errImport := HAR(harBytes, HAROptions{})
+	if errImport != nil {
+		t.Fatal(errImport)
+	}
+	params, text := service.Session[0], service.Session[1]
+	data, _ := params.Data.(map[string]interface{})
+	if params.ContentType != "multipart/form-data" || params.Headers["Content-Type"] != "" || !reflect.DeepEqual(data["tag"], []interface{}{"a", "b"}) {
+		t.Error("unexpected multipart params", params)
+	}
+	if file := params.Files["upload"]; file != "files/1-report.csv" || string(params.FileContents[file]) != "a,b" || data["upload"] != nil {
+		t.Error("file params have to become files", params.Files, data)
+	}
+	data, _ = text.Data.(map[string]interface{})
+	if text.ContentType != "multipart/form-data" || data["title"] != "report" {
+		t.Error("unexpected multipart text", text)
+	}
+}
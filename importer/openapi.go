@@ -0,0 +1,209 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foomo/petze/config"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const defaultInterval = time.Minute
+
+// OpenAPI generates a service with one call per GET operation of an OpenAPI 3 document
+// parameters are filled with their examples, defaults or values matching their schema
+// if endpoint is empty, the first server of the document is used
+func OpenAPI(document []byte, endpoint string) (service *config.Service, err error) {
+	swagger, errLoad := openapi3.NewSwaggerLoader().LoadSwaggerFromData(document)
+	if errLoad != nil {
+		return nil, errors.New("could not load OpenAPI document: " + errLoad.Error())
+	}
+	if endpoint == "" {
+		if len(swagger.Servers) == 0 {
+			return nil, errors.New("the document has no servers, an endpoint is required")
+		}
+		endpoint = swagger.Servers[0].URL
+	}
+	endpointURL, errURL := url.Parse(endpoint)
+	if errURL != nil || endpointURL.Host == "" {
+		return nil, errors.New("invalid endpoint: " + endpoint)
+	}
+
+	// the paths of the document are relative to the server url
+	basePath := strings.TrimSuffix(endpointURL.Path, "/")
+	if basePath == "" && len(swagger.Servers) > 0 {
+		serverURL, errServerURL := url.Parse(swagger.Servers[0].URL)
+		if errServerURL == nil && !strings.Contains(serverURL.Path, "{") {
+			basePath = strings.TrimSuffix(serverURL.Path, "/")
+		}
+	}
+
+	service = &config.Service{
+		Endpoint: endpointURL.Scheme + "://" + endpointURL.Host,
+		Interval: defaultInterval,
+	}
+
+	paths := []string{}
+	for path := range swagger.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		pathItem := swagger.Paths[path]
+		if pathItem.Get == nil {
+			continue
+		}
+		service.Session = append(service.Session, openAPICall(basePath, path, pathItem, pathItem.Get))
+	}
+	if len(service.Session) == 0 {
+		return nil, errors.New("the document has no GET operations")
+	}
+	return service, nil
+}
+
+func openAPICall(basePath, path string, pathItem *openapi3.PathItem, operation *openapi3.Operation) config.Call {
+	call := config.Call{
+		Comment: operation.Summary,
+	}
+	if call.Comment == "" {
+		call.Comment = operation.OperationID
+	}
+	if call.Comment == "" {
+		call.Comment = http.MethodGet + " " + path
+	}
+
+	query := url.Values{}
+	for _, parameter := range operationParameters(pathItem, operation) {
+		value := fmt.Sprint(parameterExample(parameter))
+		switch parameter.In {
+		case openapi3.ParameterInPath:
+			path = strings.Replace(path, "{"+parameter.Name+"}", url.PathEscape(value), -1)
+		case openapi3.ParameterInQuery:
+			if parameter.Required {
+				query.Set(parameter.Name, value)
+			}
+		case openapi3.ParameterInHeader:
+			if parameter.Required {
+				if call.Headers == nil {
+					call.Headers = map[string]string{}
+				}
+				call.Headers[parameter.Name] = value
+			}
+		}
+	}
+	call.URI = basePath + path
+	if len(query) > 0 {
+		call.URI += "?" + query.Encode()
+	}
+
+	statusCode, contentType := successResponse(operation)
+	if statusCode > 0 {
+		call.Check = append(call.Check, config.Check{StatusCode: statusCode})
+	}
+	if contentType != "" {
+		call.Check = append(call.Check, config.Check{ContentType: contentType})
+	}
+	return call
+}
+
+// operationParameters merges path item and operation parameters, the operation wins
+func operationParameters(pathItem *openapi3.PathItem, operation *openapi3.Operation) (parameters []*openapi3.Parameter) {
+	overridden := map[string]bool{}
+	for _, ref := range operation.Parameters {
+		if ref.Value != nil {
+			overridden[ref.Value.In+":"+ref.Value.Name] = true
+			parameters = append(parameters, ref.Value)
+		}
+	}
+	for _, ref := range pathItem.Parameters {
+		if ref.Value != nil && !overridden[ref.Value.In+":"+ref.Value.Name] {
+			parameters = append(parameters, ref.Value)
+		}
+	}
+	return parameters
+}
+
+func parameterExample(parameter *openapi3.Parameter) interface{} {
+	if parameter.Example != nil {
+		return parameter.Example
+	}
+	names := []string{}
+	for name := range parameter.Examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if example := parameter.Examples[name]; example != nil && example.Value != nil && example.Value.Value != nil {
+			return example.Value.Value
+		}
+	}
+	if parameter.Schema != nil && parameter.Schema.Value != nil {
+		return schemaExample(parameter.Schema.Value)
+	}
+	return "example"
+}
+
+func schemaExample(schema *openapi3.Schema) interface{} {
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	}
+	switch schema.Type {
+	case "integer", "number":
+		if schema.Min != nil {
+			return *schema.Min
+		}
+		return 1
+	case "boolean":
+		return true
+	default:
+		return "example"
+	}
+}
+
+// successResponse finds the first documented 2xx response and its preferred content type
+func successResponse(operation *openapi3.Operation) (statusCode int64, contentType string) {
+	keys := []string{}
+	for key := range operation.Responses {
+		if strings.HasPrefix(key, "2") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return 0, ""
+	}
+	sort.Strings(keys)
+	response := operation.Responses[keys[0]]
+	statusCode, errParse := strconv.ParseInt(keys[0], 10, 64)
+	if errParse != nil {
+		// 2XX
+		statusCode = http.StatusOK
+	}
+	if response.Value == nil {
+		return statusCode, ""
+	}
+	if response.Value.Content[config.ContentTypeJSON] != nil {
+		return statusCode, config.ContentTypeJSON
+	}
+	contentTypes := []string{}
+	for key := range response.Value.Content {
+		if !strings.Contains(key, "*") {
+			contentTypes = append(contentTypes, key)
+		}
+	}
+	sort.Strings(contentTypes)
+	if len(contentTypes) > 0 {
+		contentType = contentTypes[0]
+	}
+	return statusCode, contentType
+}
@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/foomo/petze/config"
+	"gopkg.in/yaml.v2"
+)
+
+func TestOpenAPI(t *testing.T) {
+	document, errRead := ioutil.ReadFile("testdata/shop-openapi.yaml")
+	if errRead != nil {
+		t.Fatal(errRead)
+	}
+	service, errImport := OpenAPI(document, "https://staging.shop.com")
+	if errImport != nil {
+		t.Fatal(errImport)
+	}
+	yamlBytes, errMarshal := MarshalService(service)
+	if errMarshal != nil {
+		t.Fatal(errMarshal)
+	}
+
+	// the generated yaml has to be a valid service configuration
+	loaded := &config.Service{}
+	if errUnmarshal := yaml.UnmarshalStrict(yamlBytes, loaded); errUnmarshal != nil {
+		t.Fatal(errUnmarshal, string(yamlBytes))
+	}
+	if loaded.Endpoint != "https://staging.shop.com" || loaded.Interval != defaultInterval {
+		t.Error("unexpected endpoint or interval", loaded.Endpoint, loaded.Interval)
+	}
+	if len(loaded.Session) != 2 {
+		t.Fatal("expected one call per GET operation", string(yamlBytes))
+	}
+	product := loaded.Session[1]
+	if product.URI != "/v1/products/ABC-1?locale=de" || product.Headers["X-Api-Version"] != "2" {
+		t.Error("parameters should be filled with examples", product.URI, product.Headers)
+	}
+	if len(product.Check) != 2 || product.Check[0].StatusCode != 200 || product.Check[1].ContentType != config.ContentTypeJSON {
+		t.Error("unexpected checks", product.Check)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for d, expected := range map[string]string{"1m": "1m", "1h": "1h", "90s": "1m30s", "250ms": "250ms", "1h30m": "1h30m"} {
+		duration, _ := time.ParseDuration(d)
+		if formatDuration(duration) != expected {
+			t.Error(d, formatDuration(duration))
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foomo/petze/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// recorded files are relative to the config folder
+const filesFolder = "files"
+
+// RecorderOptions control what a recorder captures
+type RecorderOptions struct {
+	// drop images, stylesheets, scripts, fonts and media
+	DropStatic bool
+}
+
+// Recorder is an http proxy, that records the traffic passing through it as a session
+// with a target it is a reverse proxy for the target, otherwise a forward proxy for plain http
+type Recorder struct {
+	target  *url.URL
+	options RecorderOptions
+	proxy   *httputil.ReverseProxy
+
+	mutex    sync.Mutex
+	endpoint *url.URL
+	calls    []config.Call
+	// cookies set by the server during the recording, they will be in the cookie jar of the session
+	cookies map[string]string
+}
+
+type recording struct {
+	start   time.Time
+	request *http.Request
+	body    []byte
+}
+
+type recordingContextKey struct{}
+
+// request resource types sent by browsers in Sec-Fetch-Dest
+var fetchDestResourceTypes = map[string]string{
+	"image":  "image",
+	"style":  "stylesheet",
+	"script": "script",
+	"font":   "font",
+	"audio":  "media",
+	"video":  "media",
+}
+
+// NewRecorder creates a recording proxy - pass an empty target to run it as a forward proxy
+func NewRecorder(target string, options RecorderOptions) (r *Recorder, err error) {
+	r = &Recorder{
+		options: options,
+		cookies: map[string]string{},
+	}
+	if target != "" {
+		targetURL, errURL := url.Parse(target)
+		if errURL != nil || targetURL.Host == "" {
+			return nil, errors.New("invalid target: " + target)
+		}
+		r.target = targetURL
+	}
+	r.proxy = &httputil.ReverseProxy{
+		Director:       r.direct,
+		ModifyResponse: r.record,
+	}
+	return r, nil
+}
+
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		http.Error(w, "https can not be recorded through a forward proxy, run petze record with a --target", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.target == nil && req.URL.Host == "" {
+		http.Error(w, "configure this as your http proxy or run petze record with a --target", http.StatusBadRequest)
+		return
+	}
+	body, errBody := ioutil.ReadAll(req.Body)
+	if errBody != nil {
+		http.Error(w, errBody.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	ctx := context.WithValue(req.Context(), recordingContextKey{}, &recording{
+		start:   time.Now(),
+		request: req,
+		body:    body,
+	})
+	r.proxy.ServeHTTP(w, req.WithContext(ctx))
+}
+
+// direct points the outgoing request at the target
+func (r *Recorder) direct(req *http.Request) {
+	if r.target == nil {
+		return
+	}
+	proxyBase := "http://" + req.Host
+	targetBase := r.target.Scheme + "://" + r.target.Host
+	req.URL.Scheme = r.target.Scheme
+	req.URL.Host = r.target.Host
+	req.Host = r.target.Host
+	// the server must not see the proxy in origin checks
+	for _, name := range []string{"Origin", "Referer"} {
+		if value := req.Header.Get(name); strings.HasPrefix(value, proxyBase) {
+			req.Header.Set(name, targetBase+strings.TrimPrefix(value, proxyBase))
+		}
+	}
+}
+
+// record the call and rewrite the response, so that the browser stays on the proxy
+func (r *Recorder) record(response *http.Response) error {
+	rec, ok := response.Request.Context().Value(recordingContextKey{}).(*recording)
+	if !ok {
+		return nil
+	}
+	duration := time.Since(rec.start)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	requestURL := response.Request.URL
+	if r.endpoint == nil {
+		r.endpoint = &url.URL{Scheme: requestURL.Scheme, Host: requestURL.Host}
+	}
+	resourceType := fetchDestResourceTypes[rec.request.Header.Get("Sec-Fetch-Dest")]
+	switch {
+	case requestURL.Host != r.endpoint.Host:
+		log.Info("not recording request to other host ", requestURL.String())
+	case r.options.DropStatic && isStatic(resourceType, requestURL, response.Header.Get("Content-Type")):
+	default:
+		call := r.recordedCall(rec, requestURL, response, duration)
+		log.Info("recorded ", rec.request.Method, " ", call.URI, " ", response.StatusCode)
+		r.calls = append(r.calls, call)
+	}
+
+	for _, cookie := range response.Cookies() {
+		r.cookies[cookie.Name] = cookie.Value
+	}
+	if r.target != nil {
+		r.rewriteResponse(rec.request, response)
+	}
+	return nil
+}
+
+func (r *Recorder) recordedCall(rec *recording, requestURL *url.URL, response *http.Response, duration time.Duration) config.Call {
+	call := config.Call{
+		URI: requestURL.RequestURI(),
+	}
+	if rec.request.Method != http.MethodGet {
+		call.Method = rec.request.Method
+	}
+	if requestURL.Scheme != r.endpoint.Scheme {
+		call.Scheme = requestURL.Scheme
+	}
+
+	for name, values := range response.Request.Header {
+		lowerName := strings.ToLower(name)
+		if isStrippedRecordingHeader(lowerName) {
+			continue
+		}
+		// recorded as contentType, a multipart boundary has to match the body of the replay
+		if lowerName == "content-type" && len(rec.body) > 0 {
+			continue
+		}
+		value := strings.Join(values, ", ")
+		if lowerName == "cookie" {
+			// cookies the server set during the recording will be in the cookie jar of the session
+			value = r.unknownCookies(response.Request)
+			if value == "" {
+				continue
+			}
+		}
+		if call.Headers == nil {
+			call.Headers = map[string]string{}
+		}
+		call.Headers[name] = value
+	}
+
+	if len(rec.body) > 0 {
+		call.ContentType = rec.request.Header.Get("Content-Type")
+		mediaType, params, _ := mime.ParseMediaType(call.ContentType)
+		switch mediaType {
+		case config.ContentTypeForm:
+			var form map[string]interface{}
+			if values, errParse := url.ParseQuery(string(rec.body)); errParse == nil {
+				form = formData(values)
+			}
+			call.Data = requestData(call.ContentType, string(rec.body), form)
+		case config.ContentTypeMultipart:
+			// the boundary changes with every request, it is generated, when the session runs
+			call.ContentType = mediaType
+			if errMultipart := multipartData(&call, len(r.calls)+1, rec.body, params["boundary"]); errMultipart != nil {
+				log.Warn("could not record multipart body of ", call.URI, ": ", errMultipart)
+			}
+		default:
+			call.Data = requestData(call.ContentType, string(rec.body), nil)
+		}
+	}
+
+	call.Check = []config.Check{
+		{StatusCode: int64(response.StatusCode)},
+		{Duration: durationCheck(duration)},
+	}
+	return call
+}
+
+// multipartData records the fields of a multipart body as data and its file parts as files of the call with the given index
+func multipartData(call *config.Call, index int, body []byte, boundary string) error {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	fields := url.Values{}
+	for {
+		part, errPart := reader.NextPart()
+		if errPart == io.EOF {
+			break
+		}
+		if errPart != nil {
+			return errPart
+		}
+		content, errRead := ioutil.ReadAll(part)
+		if errRead != nil {
+			return errRead
+		}
+		name := part.FormName()
+		if part.FileName() == "" {
+			fields.Add(name, string(content))
+			continue
+		}
+		addFile(call, index, name, part.FileName(), content)
+	}
+	if len(fields) > 0 {
+		call.Data = formData(fields)
+	}
+	return nil
+}
+
+// addFile adds a file part to the call with the given index - files of different calls must not overwrite each other
+func addFile(call *config.Call, index int, field, fileName string, content []byte) {
+	if _, exists := call.Files[field]; exists {
+		log.Warn("only the first file of ", field, " is recorded")
+		return
+	}
+	name := path.Base(filepath.ToSlash(fileName))
+	// every .yml file in the config folder is loaded as a service
+	if strings.HasSuffix(name, ".yml") {
+		name += ".upload"
+	}
+	file := path.Join(filesFolder, fmt.Sprint(index, "-", name))
+	if call.Files == nil {
+		call.Files = map[string]string{}
+		call.FileContents = map[string][]byte{}
+	}
+	call.Files[field] = file
+	call.FileContents[file] = content
+}
+
+// MoveFiles moves the files of the calls into dir - use the id of the service, so that services do not share files
+func MoveFiles(service *config.Service, dir string) {
+	for i := range service.Session {
+		call := &service.Session[i]
+		if len(call.Files) == 0 {
+			continue
+		}
+		contents := map[string][]byte{}
+		for field, file := range call.Files {
+			moved := path.Join(dir, path.Base(file))
+			call.Files[field] = moved
+			contents[moved] = call.FileContents[file]
+		}
+		call.FileContents = contents
+	}
+}
+
+// formData converts form values to data - repeated fields become lists
+func formData(values url.Values) map[string]interface{} {
+	data := map[string]interface{}{}
+	for name, fieldValues := range values {
+		if len(fieldValues) == 1 {
+			data[name] = fieldValues[0]
+			continue
+		}
+		list := make([]interface{}, len(fieldValues))
+		for i, value := range fieldValues {
+			list[i] = value
+		}
+		data[name] = list
+	}
+	return data
+}
+
+func isStrippedRecordingHeader(name string) bool {
+	if strings.HasPrefix(name, "sec-") || strings.HasPrefix(name, "x-forwarded-") || name == "upgrade-insecure-requests" {
+		return true
+	}
+	for _, stripped := range clientHeaders {
+		if name == stripped {
+			return true
+		}
+	}
+	for _, volatile := range volatileHeaders {
+		if name == volatile && name != "cookie" {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownCookies returns the cookies of a request, that were not set during the recording
+func (r *Recorder) unknownCookies(req *http.Request) string {
+	unknown := []string{}
+	for _, cookie := range req.Cookies() {
+		if value, known := r.cookies[cookie.Name]; !known || value != cookie.Value {
+			unknown = append(unknown, cookie.Name+"="+cookie.Value)
+		}
+	}
+	return strings.Join(unknown, "; ")
+}
+
+// rewriteResponse keeps redirects and cookies on the proxy
+func (r *Recorder) rewriteResponse(req *http.Request, response *http.Response) {
+	if location := response.Header.Get("Location"); location != "" {
+		locationURL, errURL := url.Parse(location)
+		if errURL == nil && locationURL.Host == r.target.Host {
+			locationURL.Scheme = "http"
+			locationURL.Host = req.Host
+			response.Header.Set("Location", locationURL.String())
+		}
+	}
+	cookies := response.Header["Set-Cookie"]
+	for i, cookie := range cookies {
+		attributes := []string{}
+		for _, attribute := range strings.Split(cookie, ";") {
+			name := strings.ToLower(strings.TrimSpace(strings.SplitN(attribute, "=", 2)[0]))
+			if name != "domain" && name != "secure" {
+				attributes = append(attributes, attribute)
+			}
+		}
+		cookies[i] = strings.Join(attributes, ";")
+	}
+}
+
+// Service returns the recorded session
+func (r *Recorder) Service() (service *config.Service, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.calls) == 0 {
+		return nil, errors.New("nothing was recorded")
+	}
+	return &config.Service{
+		Endpoint: r.endpoint.String(),
+		Interval: defaultInterval,
+		Session:  append([]config.Call{}, r.calls...),
+	}, nil
+}
+
+// durationCheck allows twice the recorded duration, rounded up to 100ms
+func durationCheck(recorded time.Duration) time.Duration {
+	const step = 100 * time.Millisecond
+	return (2*recorded/step + 1) * step
+}
@@ -0,0 +1,213 @@
+package importer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foomo/petze/config"
+	"github.com/foomo/petze/watch"
+)
+
+func TestRecorder(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if r.Method == http.MethodPost && r.FormValue("user") == "petze" {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t", Domain: "127.0.0.1", Path: "/"})
+				http.Redirect(w, r, "http://"+r.Host+"/account", http.StatusSeeOther)
+				return
+			}
+			w.Write([]byte("<form></form>"))
+		case "/account":
+			if cookie, errCookie := r.Cookie("session"); errCookie != nil || cookie.Value != "s3cr3t" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte("welcome"))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+		}
+	}))
+	defer upstream.Close()
+
+	recorder, errRecorder := NewRecorder(upstream.URL, RecorderOptions{DropStatic: true})
+	if errRecorder != nil {
+		t.Fatal(errRecorder)
+	}
+	proxy := httptest.NewServer(recorder)
+	defer proxy.Close()
+
+	jar, _ := cookiejar.New(nil)
+	browser := &http.Client{Jar: jar}
+	for _, request := range []func() (*http.Response, error){
+		func() (*http.Response, error) { return browser.Get(proxy.URL + "/login?next=account") },
+		func() (*http.Response, error) { return browser.Get(proxy.URL + "/logo.png") },
+		func() (*http.Response, error) {
+			return browser.PostForm(proxy.URL+"/login", url.Values{"user": {"petze"}})
+		},
+	} {
+		response, errRequest := request()
+		if errRequest != nil {
+			t.Fatal(errRequest)
+		}
+		response.Body.Close()
+		// the redirect must stay on the proxy and carry the session cookie
+		if response.StatusCode != http.StatusOK {
+			t.Fatal("unexpected status through proxy", response.Request.URL, response.StatusCode)
+		}
+	}
+
+	service, errService := recorder.Service()
+	if errService != nil {
+		t.Fatal(errService)
+	}
+	if service.Endpoint != upstream.URL {
+		t.Error("unexpected endpoint", service.Endpoint)
+	}
+	if len(service.Session) != 3 {
+		t.Fatal("unexpected number of calls", len(service.Session))
+	}
+	login, post, account := service.Session[0], service.Session[1], service.Session[2]
+	if login.URI != "/login?next=account" || login.Method != "" || login.Check[0].StatusCode != 200 || login.Check[1].Duration < 100*time.Millisecond {
+		t.Error("unexpected login call", login)
+	}
+	data, _ := post.Data.(map[string]interface{})
+	if post.Method != "POST" || !strings.HasPrefix(post.ContentType, "application/x-www-form-urlencoded") || data["user"] != "petze" || post.Check[0].StatusCode != 303 {
+		t.Error("unexpected form post", post)
+	}
+	// the session cookie was set during the recording, the cookie jar of the session will send it
+	if account.URI != "/account" || account.Headers["Cookie"] != "" || account.Headers["Referer"] != "" || account.Check[0].StatusCode != 200 {
+		t.Error("unexpected account call", account)
+	}
+}
+
+func TestRecorderNothingRecorded(t *testing.T) {
+	recorder, _ := NewRecorder("", RecorderOptions{})
+	if _, errService := recorder.Service(); errService == nil {
+		t.Error("an empty recording should be an error")
+	}
+	if _, errRecorder := NewRecorder("not a url", RecorderOptions{}); errRecorder == nil {
+		t.Error("invalid target should be an error")
+	}
+}
+
+func TestDurationCheck(t *testing.T) {
+	for recorded, expected := range map[time.Duration]time.Duration{
+		0:                      100 * time.Millisecond,
+		30 * time.Millisecond:  100 * time.Millisecond,
+		50 * time.Millisecond:  200 * time.Millisecond,
+		420 * time.Millisecond: 900 * time.Millisecond,
+	} {
+		if actual := durationCheck(recorded); actual != expected {
+			t.Error("unexpected duration check for", recorded, actual, "!=", expected)
+		}
+	}
+}
+
+func TestRecorderMultipartReplay(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if errParse := r.ParseMultipartForm(1 << 20); errParse != nil {
+			http.Error(w, errParse.Error(), http.StatusBadRequest)
+			return
+		}
+		file, _, errFile := r.FormFile("upload")
+		if errFile != nil {
+			http.Error(w, errFile.Error(), http.StatusBadRequest)
+			return
+		}
+		content, _ := ioutil.ReadAll(file)
+		if strings.Join(r.MultipartForm.Value["tag"], ",") != "a,b" || r.FormValue("title") != "report" || string(content) != "a,b\n" {
+			http.Error(w, "unexpected upload", http.StatusBadRequest)
+		}
+	}))
+	defer upstream.Close()
+
+	recorder, errRecorder := NewRecorder(upstream.URL, RecorderOptions{})
+	if errRecorder != nil {
+		t.Fatal(errRecorder)
+	}
+	proxy := httptest.NewServer(recorder)
+	defer proxy.Close()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("tag", "a")
+	writer.WriteField("tag", "b")
+	writer.WriteField("title", "report")
+	part, _ := writer.CreateFormFile("upload", "report.csv")
+	part.Write([]byte("a,b\n"))
+	writer.Close()
+	response, errPost := http.Post(proxy.URL+"/upload", writer.FormDataContentType(), body)
+	if errPost != nil {
+		t.Fatal(errPost)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatal("unexpected status of the recording", response.StatusCode)
+	}
+
+	service, errService := recorder.Service()
+	if errService != nil {
+		t.Fatal(errService)
+	}
+	upload := service.Session[0]
+	data, _ := upload.Data.(map[string]interface{})
+	if upload.ContentType != "multipart/form-data" || !reflect.DeepEqual(data["tag"], []interface{}{"a", "b"}) || upload.Files["upload"] == "" {
+		t.Fatal("unexpected multipart call", upload)
+	}
+
+	// write the recording like petze record does and replay it
+	MoveFiles(service, "files/recorded")
+	upload = service.Session[0]
+	if upload.Files["upload"] != "files/recorded/1-report.csv" {
+		t.Error("files have to be moved into the folder of the service", upload.Files)
+	}
+	dir, errDir := ioutil.TempDir("", "petze-record")
+	if errDir != nil {
+		t.Fatal(errDir)
+	}
+	defer os.RemoveAll(dir)
+	yamlBytes, errMarshal := MarshalService(service)
+	if errMarshal != nil {
+		t.Fatal(errMarshal)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "recorded.yml"), yamlBytes, 0644)
+	for file, content := range upload.FileContents {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, file), content, 0644)
+	}
+	services, errLoad := config.LoadServices(dir)
+	if errLoad != nil {
+		t.Fatal(errLoad)
+	}
+	chanResult := make(chan watch.Result)
+	w := watch.Watch(services["recorded"], chanResult)
+	defer w.Stop()
+	select {
+	case r := <-chanResult:
+		if len(r.Errors) > 0 {
+			t.Error("replay failed", r.Errors)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no result")
+	}
+}
+
+func TestAddFile(t *testing.T) {
+	call := config.Call{}
+	addFile(&call, 2, "upload", "../config/service.yml", []byte("endpoint: x"))
+	if file := call.Files["upload"]; file != "files/2-service.yml.upload" || string(call.FileContents[file]) != "endpoint: x" {
+		t.Error("uploaded service files must not be loaded as services", call.Files)
+	}
+}
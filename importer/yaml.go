@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/foomo/petze/config"
+	"gopkg.in/yaml.v2"
+)
+
+// MarshalService renders a service configuration, that was generated by an importer, as readable yaml
+// only the fields importers set are rendered, durations are formatted like 1m30s
+func MarshalService(service *config.Service) ([]byte, error) {
+	doc := yaml.MapSlice{
+		{Key: "endpoint", Value: service.Endpoint},
+	}
+	if service.Interval > 0 {
+		doc = append(doc, yaml.MapItem{Key: "interval", Value: formatDuration(service.Interval)})
+	}
+	session := []yaml.MapSlice{}
+	for _, call := range service.Session {
+		session = append(session, marshalCall(call))
+	}
+	doc = append(doc, yaml.MapItem{Key: "session", Value: session})
+	return yaml.Marshal(doc)
+}
+
+func marshalCall(call config.Call) yaml.MapSlice {
+	c := yaml.MapSlice{}
+	if call.Comment != "" {
+		c = append(c, yaml.MapItem{Key: "comment", Value: call.Comment})
+	}
+	if call.Scheme != "" {
+		c = append(c, yaml.MapItem{Key: "scheme", Value: call.Scheme})
+	}
+	if call.Method != "" {
+		c = append(c, yaml.MapItem{Key: "method", Value: call.Method})
+	}
+	c = append(c, yaml.MapItem{Key: "uri", Value: call.URI})
+	if len(call.Headers) > 0 {
+		c = append(c, yaml.MapItem{Key: "headers", Value: sortedMap(call.Headers)})
+	}
+	if call.ContentType != "" {
+		c = append(c, yaml.MapItem{Key: "contentType", Value: call.ContentType})
+	}
+	if call.Data != nil {
+		c = append(c, yaml.MapItem{Key: "data", Value: call.Data})
+	}
+	if len(call.Files) > 0 {
+		c = append(c, yaml.MapItem{Key: "files", Value: sortedMap(call.Files)})
+	}
+	checks := []yaml.MapSlice{}
+	for _, chk := range call.Check {
+		checks = append(checks, marshalCheck(chk)...)
+	}
+	if len(checks) > 0 {
+		c = append(c, yaml.MapItem{Key: "check", Value: checks})
+	}
+	return c
+}
+
+// marshalCheck renders one list entry per check, like the hand written configs do
+func marshalCheck(chk config.Check) (checks []yaml.MapSlice) {
+	if chk.StatusCode != 0 {
+		checks = append(checks, yaml.MapSlice{{Key: "statusCode", Value: chk.StatusCode}})
+	}
+	if chk.ContentType != "" {
+		checks = append(checks, yaml.MapSlice{{Key: "contentType", Value: chk.ContentType}})
+	}
+	if chk.Redirect != "" {
+		checks = append(checks, yaml.MapSlice{{Key: "redirect", Value: chk.Redirect}})
+	}
+	if chk.Duration > 0 {
+		checks = append(checks, yaml.MapSlice{{Key: "duration", Value: formatDuration(chk.Duration)}})
+	}
+	return checks
+}
+
+func sortedMap(m map[string]string) yaml.MapSlice {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sorted := yaml.MapSlice{}
+	for _, key := range keys {
+		sorted = append(sorted, yaml.MapItem{Key: key, Value: m[key]})
+	}
+	return sorted
+}
+
+// formatDuration drops zero units: 1m instead of 1m0s
+func formatDuration(d time.Duration) string {
+	formatted := d.String()
+	if strings.HasSuffix(formatted, "m0s") {
+		formatted = strings.TrimSuffix(formatted, "0s")
+	}
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = strings.TrimSuffix(formatted, "0m")
+	}
+	return formatted
+}
@@ -2,6 +2,7 @@ package mail
 
 import (
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/matcornic/hermes/v2"
@@ -13,9 +14,13 @@ import (
 var (
 	m   *Mailer
 	Log = logrus.New()
+	// sending holds a read lock, so the mailer is not replaced while mails are sent
+	mutex sync.RWMutex
 )
 
 func IsInitialized() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
 	return m != nil
 }
 
@@ -56,6 +61,8 @@ func ConfigureLogger(level logrus.Level, prod bool) {
 
 // InitMailer returns a new mailer instance
 func InitMailer(smtpServer, smtpUser, smtpPassword, from string, smtpPort int, to []string) {
+	mutex.Lock()
+	defer mutex.Unlock()
 	m = &Mailer{
 		server:   smtpServer,
 		port:     smtpPort,
@@ -67,14 +74,36 @@ func InitMailer(smtpServer, smtpUser, smtpPassword, from string, smtpPort int, t
 	m.dialer = gomail.NewDialer(m.server, m.port, m.user, m.password)
 }
 
+// Disable stops sending mails, until the mailer is initialized again
+func Disable() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	m = nil
+}
+
 func SendMails(subject string, mail hermes.Email) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if m == nil {
+		return
+	}
 	for _, recipient := range m.to {
-		Send(recipient, subject, mail)
+		send(recipient, subject, mail)
 	}
 }
 
 // Send handles dispatching an email to the specified receiver
 func Send(to string, subject string, mail hermes.Email) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if m == nil {
+		return
+	}
+	send(to, subject, mail)
+}
+
+// send expects the read lock to be held
+func send(to string, subject string, mail hermes.Email) {
 
 	cLog := Log.WithFields(logrus.Fields{
 		"prefix":  "mailer",
@@ -116,7 +145,7 @@ func Send(to string, subject string, mail hermes.Email) {
 		// prevent loop
 		if to != m.from {
 			// notify grand master
-			Send(m.from, "[Mail Error] "+subject+" to "+to, GenerateErrorMail([]error{err}, "failed to send mail", "internal"))
+			send(m.from, "[Mail Error] "+subject+" to "+to, GenerateErrorMail([]error{err}, "failed to send mail", "internal"))
 		}
 	}
 }
@@ -1,16 +1,16 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"github.com/foomo/petze/mail"
-	"github.com/foomo/petze/sms"
 	"github.com/foomo/petze/watch"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/foomo/petze/config"
 	"github.com/foomo/petze/service"
-	"github.com/foomo/petze/slack"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -30,6 +30,15 @@ func main() {
 		log.Fatal("please pass the configuration directory as a first argument")
 	}
 
+	if flag.Arg(0) == "record" {
+		runRecord(flag.Args()[1:])
+		return
+	}
+	if flag.Arg(0) == "import" {
+		runImport(flag.Args()[1:])
+		return
+	}
+
 	// add version to user agent
 	watch.SetUserAgentVersion(Version)
 	fmt.Println("petze", Version, "starting")
@@ -47,30 +56,27 @@ func runServer(configurationDirectory string) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	if serverConfig.SMTP != nil {
-		// init mailer
-		mail.InitMailer(
-			serverConfig.SMTP.Server,
-			serverConfig.SMTP.User,
-			serverConfig.SMTP.Pass,
-			serverConfig.SMTP.From,
-			serverConfig.SMTP.Port,
-			serverConfig.SMTP.To,
-		)
-	}
-	// init slackbot
-	if serverConfig.Slack != "" {
-		slack.InitSlackBot(serverConfig.Slack)
-	}
-	// init SMS
-	if serverConfig.Sms != nil {
-		sms.InitSMS(serverConfig.Sms)
+	service.ConfigureNotifications(serverConfig)
+
+	// stop gracefully on SIGTERM and ctrl+c
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		log.Info("received ", <-signals)
+		cancel()
+	}()
+	if errRun := service.Run(ctx, serverConfig, configurationDirectory); errRun != nil {
+		log.Fatal(errRun)
 	}
-	log.Info(service.Run(serverConfig, configurationDirectory))
+	log.Info("petze stopped")
 }
 
 func usage() {
 	log.Printf("Usage: %s configuration-directory \n", os.Args[0])
+	log.Printf("       %s import openapi <spec> [--endpoint <url>] [--out <file>] \n", os.Args[0])
+	log.Printf("       %s import har <file.har> [--drop-static] [--strip-volatile-headers] [--strip-header <names>] [--out <file>] \n", os.Args[0])
+	log.Printf("       %s record [--target <url>] [--listen <address>] [--keep-static] [--out <file>] \n", os.Args[0])
 	flag.PrintDefaults()
 }
 
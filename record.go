@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/foomo/petze/importer"
+	log "github.com/sirupsen/logrus"
+)
+
+// runRecord runs a recording proxy until it is interrupted and writes the recorded session
+func runRecord(args []string) {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	target := fs.String("target", "", "url to record, the proxy forwards to it - without a target petze runs as a plain http forward proxy")
+	listen := fs.String("listen", "127.0.0.1:8081", "address of the recording proxy")
+	keepStatic := fs.Bool("keep-static", false, "also record images, stylesheets, scripts, fonts and media")
+	out := fs.String("out", "", "service configuration file to write, defaults to stdout")
+	if positional := parseInterspersed(fs, args); len(positional) != 0 {
+		log.Fatal("usage: petze record [--target <url>] [--listen <address>] [--keep-static] [--out <file>]")
+	}
+
+	recorder, errRecorder := importer.NewRecorder(*target, importer.RecorderOptions{DropStatic: !*keepStatic})
+	if errRecorder != nil {
+		log.Fatal(errRecorder)
+	}
+	server := &http.Server{Addr: *listen, Handler: recorder}
+	go func() {
+		if errListen := server.ListenAndServe(); errListen != nil && errListen != http.ErrServerClosed {
+			log.Fatal(errListen)
+		}
+	}()
+	if *target != "" {
+		log.Info("recording ", *target, " - browse http://", *listen, " and press ctrl+c when you are done")
+	} else {
+		log.Info("recording through the http proxy ", *listen, " - press ctrl+c when you are done")
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	if errShutdown := server.Shutdown(context.Background()); errShutdown != nil {
+		log.Warn("could not shut down the recording proxy: ", errShutdown)
+	}
+
+	service, errService := recorder.Service()
+	if errService != nil {
+		log.Fatal(errService)
+	}
+	writeService(service, *out)
+}
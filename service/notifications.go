@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/foomo/petze/config"
+	"github.com/foomo/petze/mail"
+	"github.com/foomo/petze/slack"
+	"github.com/foomo/petze/sms"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ConfigureNotifications (re)initializes mail, slack and sms - channels missing in c are disabled
+func ConfigureNotifications(c *config.Server) {
+	if c.SMTP != nil {
+		mail.InitMailer(
+			c.SMTP.Server,
+			c.SMTP.User,
+			c.SMTP.Pass,
+			c.SMTP.From,
+			c.SMTP.Port,
+			c.SMTP.To,
+		)
+	} else {
+		mail.Disable()
+	}
+	slack.InitSlackBot(c.Slack)
+	sms.InitSMS(c.Sms)
+}
+
+// reloadServerConfig applies the notification settings of petze.yml, listeners and limits require a restart
+func reloadServerConfig(configDir string) error {
+	c, err := config.LoadServer(configDir)
+	if err != nil {
+		return errors.New("could not reload server configuration: " + err.Error())
+	}
+	ConfigureNotifications(c)
+	log.Info("server configuration reloaded")
+	return nil
+}
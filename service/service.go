@@ -1,9 +1,14 @@
 package service
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	auth "github.com/abbot/go-http-auth"
 	"github.com/foomo/petze/collector"
@@ -11,6 +16,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 
 	"github.com/foomo/petze/exporter"
+	"github.com/foomo/petze/watch"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	log "github.com/sirupsen/logrus"
@@ -31,8 +37,12 @@ type server struct {
 	collector *collector.Collector
 }
 
-func newServer(servicesConfigfile string) (s *server, err error) {
+func newServer(c *config.Server, servicesConfigfile string) (s *server, err error) {
 	coll, err := collector.NewCollector(servicesConfigfile)
+	coll.SetConcurrencyLimits(c.MaxSessions, c.MaxSessionsPerHost)
+	coll.OnServerConfigChange(func() error {
+		return reloadServerConfig(servicesConfigfile)
+	})
 	defer coll.Start()
 	s = &server{
 		router:    httprouter.New(),
@@ -100,10 +110,13 @@ func getTLSConfig() *tls.Config {
 	return c
 }
 
-// Run as a server
-func Run(c *config.Server, servicesConfigfile string) error {
+// shutdownTimeout limits the time to finish http requests and to send pending notifications
+const shutdownTimeout = 30 * time.Second
 
-	s, err := newServer(servicesConfigfile)
+// Run as a server until ctx is done, then stop accepting requests, stop all watchers and drain notifications
+func Run(ctx context.Context, c *config.Server, servicesConfigfile string) error {
+
+	s, err := newServer(c, servicesConfigfile)
 	if err != nil {
 		return err
 	}
@@ -119,23 +132,65 @@ func Run(c *config.Server, servicesConfigfile string) error {
 
 	ba := newBasicAuthHandler(s, c.BasicAuthFile)
 
-	errorChan := make(chan (error))
+	servers := []*http.Server{}
+	errorChan := make(chan (error), 2)
 	if len(c.Address) > 0 {
+		httpServer := &http.Server{
+			Addr:    c.Address,
+			Handler: ba,
+		}
+		servers = append(servers, httpServer)
 		go func() {
-			errorChan <- http.ListenAndServe(c.Address, ba)
+			errorChan <- httpServer.ListenAndServe()
 		}()
 	}
 
 	if c.TLS != nil {
+		log.Info("tls is configured: ", c.TLS)
+		tlsServer := &http.Server{
+			Addr:      c.TLS.Address,
+			Handler:   ba,
+			TLSConfig: getTLSConfig(),
+		}
+		servers = append(servers, tlsServer)
 		go func() {
-			log.Info("tls is configured: ", c.TLS)
-			tlsServer := &http.Server{
-				Addr:      c.TLS.Address,
-				Handler:   ba,
-				TLSConfig: getTLSConfig(),
-			}
 			errorChan <- tlsServer.ListenAndServeTLS(c.TLS.Cert, c.TLS.Key)
 		}()
 	}
-	return <-errorChan
+
+	// reload the configuration on SIGHUP
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	go func() {
+		for {
+			select {
+			case <-hangups:
+				log.Info("reloading the configuration")
+				s.collector.Reload()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var errServe error
+	select {
+	case errServe = <-errorChan:
+	case <-ctx.Done():
+		log.Info("shutting down")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if errShutdown := server.Shutdown(shutdownCtx); errShutdown != nil {
+			log.Warn("could not shut down server on ", server.Addr, " : ", errShutdown)
+		}
+	}
+	s.collector.Stop()
+	if errDrain := watch.DrainNotifications(shutdownCtx); errDrain != nil {
+		log.Warn(errDrain)
+	}
+	return errServe
 }
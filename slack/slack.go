@@ -3,20 +3,26 @@ package slack
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/foomo/petze/config"
 	"github.com/sirupsen/logrus"
 	prefixed "github.com/x-cray/logrus-prefixed-formatter"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
 var (
 	Log     = logrus.New()
 	webhook string
+	// sending holds a read lock, so the webhook is not replaced while a message is sent
+	mutex sync.RWMutex
 )
 
 func IsInitialized() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
 	return webhook != ""
 }
 
@@ -48,11 +54,19 @@ func ConfigureLogger(level logrus.Level, prod bool) {
 
 const timestampFormat = "Mon 2 Jan 2006 15:04:05"
 
+// InitSlackBot sets the webhook, an empty one disables slack
 func InitSlackBot(w string) {
+	mutex.Lock()
+	defer mutex.Unlock()
 	webhook = w
 }
 
 func Send(message []byte) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if webhook == "" {
+		return
+	}
 	client := &http.Client{}
 	requestBody := bytes.NewReader(message)
 	request, err := http.NewRequest("POST", webhook, requestBody)
@@ -65,7 +79,8 @@ func Send(message []byte) {
 	// execute the HTTP request
 	response, err := client.Do(request)
 	if err != nil {
-		Log.Error(err)
+		// the error contains the webhook url
+		Log.Error(config.Redact(err.Error()))
 	}
 	defer response.Body.Close()
 
@@ -1,22 +1,38 @@
 package sms
 
 import (
+	"sync"
+
 	"github.com/foomo/petze/config"
 )
 
 const timestampFormat = "Mon 2 Jan 2006 15:04:05"
 
-var conf *config.SMS
+var (
+	conf *config.SMS
+	// sending holds a read lock, so the configuration is not replaced while messages are sent
+	mutex sync.RWMutex
+)
 
 func IsInitialized() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
 	return conf != nil
 }
 
+// InitSMS sets the configuration, nil disables sms
 func InitSMS(c *config.SMS) {
+	mutex.Lock()
+	defer mutex.Unlock()
 	conf = c
 }
 
 func SendErrors(errs []error, service string) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if conf == nil {
+		return
+	}
 	if conf.TwilioSID != "" && conf.TwilioToken != "" {
 		SendTwilioSMS(GenerateTwilioErrorSMS(errs, service))
 	}
@@ -26,6 +42,11 @@ func SendErrors(errs []error, service string) {
 }
 
 func SendResolvedNotification(service string) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if conf == nil {
+		return
+	}
 	if conf.TwilioSID != "" && conf.TwilioToken != "" {
 		SendTwilioSMS(GenerateTwilioResolvedSMS(service))
 	}
@@ -1,6 +1,7 @@
 package sms
 
 import (
+	"github.com/foomo/petze/config"
 	"github.com/kevinburke/twilio-go"
 	"log"
 	"strings"
@@ -64,7 +65,7 @@ func SendTwilioSMS(sms []*TwilioSMS) {
 		// Send a message
 		_, err := client.Messages.SendMessage(conf.From, s.To, s.Body, nil)
 		if err != nil {
-			log.Println("sending twilio sms failed:", err)
+			log.Println("sending twilio sms failed:", config.Redact(err.Error()))
 		}
 		//fmt.Println(msg.Status)
 	}
@@ -0,0 +1,141 @@
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/foomo/petze/config"
+)
+
+const contentTypeOctetStream = "application/octet-stream"
+
+// requestBody encodes the data of a call following its content type - without one data is sent as JSON
+func requestBody(call config.Call) (body io.Reader, contentType string, err error) {
+	if call.DataFile != "" {
+		contentType = call.ContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(call.DataFile))
+		}
+		if contentType == "" {
+			contentType = contentTypeOctetStream
+		}
+		return bytes.NewReader(call.FileContents[call.DataFile]), contentType, nil
+	}
+	if call.Data == nil && len(call.Files) == 0 {
+		return nil, call.ContentType, nil
+	}
+
+	contentType = call.ContentType
+	if contentType == "" {
+		contentType = config.ContentTypeJSON
+	}
+	mediaType, _, errParse := mime.ParseMediaType(contentType)
+	if errParse != nil {
+		return nil, "", errors.New("invalid contentType " + contentType + " : " + errParse.Error())
+	}
+	switch {
+	case mediaType == config.ContentTypeForm:
+		values, errValues := formValues(call.Data)
+		if errValues != nil {
+			return nil, "", errValues
+		}
+		return strings.NewReader(values.Encode()), contentType, nil
+	case mediaType == config.ContentTypeMultipart:
+		return multipartBody(call)
+	case mediaType == config.ContentTypeJSON || strings.HasSuffix(mediaType, "+json"):
+		dataBytes, errDataBytes := json.Marshal(call.Data)
+		if errDataBytes != nil {
+			return nil, "", errors.New("could not encode data bytes: " + errDataBytes.Error())
+		}
+		return bytes.NewReader(dataBytes), contentType, nil
+	default:
+		// text, xml and everything else is sent as it is
+		data, ok := call.Data.(string)
+		if !ok {
+			return nil, "", errors.New("data has to be a string for contentType " + contentType)
+		}
+		return strings.NewReader(data), contentType, nil
+	}
+}
+
+// formValues converts a data map to form values - lists become repeated fields
+func formValues(data interface{}) (values url.Values, err error) {
+	values = url.Values{}
+	if data == nil {
+		return values, nil
+	}
+	fields, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("form data has to be a map of fields")
+	}
+	for name, value := range fields {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, element := range v {
+				values.Add(name, formValue(element))
+			}
+		default:
+			values.Add(name, formValue(v))
+		}
+	}
+	return values, nil
+}
+
+func formValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func multipartBody(call config.Call) (body io.Reader, contentType string, err error) {
+	values, errValues := formValues(call.Data)
+	if errValues != nil {
+		return nil, "", errValues
+	}
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+
+	// sorted for reproducible bodies
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range values[name] {
+			if errField := writer.WriteField(name, value); errField != nil {
+				return nil, "", errField
+			}
+		}
+	}
+
+	names = []string{}
+	for name := range call.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		file := call.Files[name]
+		part, errPart := writer.CreateFormFile(name, filepath.Base(file))
+		if errPart != nil {
+			return nil, "", errPart
+		}
+		if _, errWrite := part.Write(call.FileContents[file]); errWrite != nil {
+			return nil, "", errWrite
+		}
+	}
+	if errClose := writer.Close(); errClose != nil {
+		return nil, "", errClose
+	}
+	return buf, writer.FormDataContentType(), nil
+}
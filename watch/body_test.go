@@ -0,0 +1,97 @@
+package watch
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"testing"
+
+	"github.com/foomo/petze/config"
+)
+
+func TestRequestBody(t *testing.T) {
+	tests := []struct {
+		name                string
+		call                config.Call
+		expectedBody        string
+		expectedContentType string
+	}{
+		{
+			name:                "json by default",
+			call:                config.Call{Data: map[string]interface{}{"foo": "bar"}},
+			expectedBody:        `{"foo":"bar"}`,
+			expectedContentType: config.ContentTypeJSON,
+		},
+		{
+			name: "form",
+			call: config.Call{
+				ContentType: config.ContentTypeForm,
+				Data:        map[string]interface{}{"user": "petze", "remember": true, "tags": []interface{}{"a", "b"}},
+			},
+			expectedBody:        "remember=true&tags=a&tags=b&user=petze",
+			expectedContentType: config.ContentTypeForm,
+		},
+		{
+			name:                "raw xml",
+			call:                config.Call{ContentType: "text/xml; charset=utf-8", Data: "<ping/>"},
+			expectedBody:        "<ping/>",
+			expectedContentType: "text/xml; charset=utf-8",
+		},
+		{
+			name:                "data file",
+			call:                config.Call{DataFile: "soap/request.xml", FileContents: map[string][]byte{"soap/request.xml": []byte("<envelope/>")}},
+			expectedBody:        "<envelope/>",
+			expectedContentType: "text/xml; charset=utf-8",
+		},
+		{
+			name: "no body",
+		},
+	}
+	for _, test := range tests {
+		body, contentType, err := requestBody(test.call)
+		if err != nil {
+			t.Error(test.name, err)
+			continue
+		}
+		if contentType != test.expectedContentType {
+			t.Error(test.name, "unexpected content type", contentType)
+		}
+		if body == nil {
+			if test.expectedBody != "" {
+				t.Error(test.name, "missing body")
+			}
+			continue
+		}
+		bodyBytes, _ := ioutil.ReadAll(body)
+		if string(bodyBytes) != test.expectedBody {
+			t.Error(test.name, "unexpected body", string(bodyBytes))
+		}
+	}
+
+	if _, _, err := requestBody(config.Call{ContentType: "text/plain", Data: map[string]interface{}{"foo": "bar"}}); err == nil {
+		t.Error("raw bodies have to be strings")
+	}
+}
+
+func TestRequestBodyMultipart(t *testing.T) {
+	body, contentType, err := requestBody(config.Call{
+		ContentType:  config.ContentTypeMultipart,
+		Data:         map[string]interface{}{"title": "report"},
+		Files:        map[string]string{"upload": "files/report.csv"},
+		FileContents: map[string][]byte{"files/report.csv": []byte("a,b\n1,2\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if mediaType != config.ContentTypeMultipart || params["boundary"] == "" {
+		t.Fatal("unexpected content type", contentType)
+	}
+	form, errForm := multipart.NewReader(body, params["boundary"]).ReadForm(1024)
+	if errForm != nil {
+		t.Fatal(errForm)
+	}
+	if form.Value["title"][0] != "report" || len(form.File["upload"]) != 1 || form.File["upload"][0].Filename != "report.csv" {
+		t.Error("unexpected form", form.Value, form.File)
+	}
+}
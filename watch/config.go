@@ -0,0 +1,26 @@
+package watch
+
+import (
+	"github.com/foomo/petze/config"
+)
+
+// ConfigID identifies the results of loading the configuration - hidden files are not loaded, so no service can have it
+const ConfigID = ".config"
+
+// NewConfigWatcher creates a watcher, that is not started, to notify about configuration errors with Notify
+func NewConfigWatcher() *Watcher {
+	return NewWatcher(&config.Service{
+		ID:               ConfigID,
+		NotifyIfResolved: true,
+	})
+}
+
+// ConfigResult reports errors loading the configuration, without errors it reports, that the configuration is fine again
+func ConfigResult(errs ...error) *Result {
+	r := NewResult(ConfigID)
+	for _, err := range errs {
+		r.addError(err, ErrorTypeConfig, "the previous configuration is still used")
+	}
+	r.redact()
+	return r
+}
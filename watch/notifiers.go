@@ -1,13 +1,68 @@
 package watch
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync"
+
 	"github.com/foomo/petze/mail"
 	"github.com/foomo/petze/slack"
 	"github.com/foomo/petze/sms"
+
+	log "github.com/sirupsen/logrus"
 )
 
+// notificationQueue sends notifications in the background, they are drained on shutdown
+type notificationQueue struct {
+	sync.Mutex
+	pending sync.WaitGroup
+	closed  bool
+}
+
+var notifications = &notificationQueue{}
+
+func sendNotification(send func()) {
+	notifications.send(send)
+}
+
+// DrainNotifications stops accepting notifications and waits for the pending ones until ctx is done
+func DrainNotifications(ctx context.Context) error {
+	return notifications.drain(ctx)
+}
+
+func (q *notificationQueue) send(send func()) {
+	q.Lock()
+	defer q.Unlock()
+	if q.closed {
+		log.Warn("dropping notification, petze is shutting down")
+		return
+	}
+	q.pending.Add(1)
+	go func() {
+		defer q.pending.Done()
+		send()
+	}()
+}
+
+func (q *notificationQueue) drain(ctx context.Context) error {
+	q.Lock()
+	q.closed = true
+	q.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		q.pending.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return errors.New("not all notifications could be sent: " + ctx.Err().Error())
+	}
+}
+
 func (w *Watcher) smsNotify(r *Result) {
 
 	// if SMS notifications are not enabled, return immediately
@@ -28,9 +83,9 @@ func (w *Watcher) smsNotify(r *Result) {
 		}
 
 		if !w.didReceiveSMSNotification || w.didErrorsChange(r) {
-			go func() {
+			sendNotification(func() {
 				sms.SendErrors(errs, w.service.ID)
-			}()
+			})
 			w.didReceiveSMSNotification = true
 			w.lastErrors = r.Errors
 		}
@@ -42,9 +97,9 @@ func (w *Watcher) smsNotify(r *Result) {
 			w.lastErrors = []Error{}
 
 			if w.service.NotifyIfResolved {
-				go func() {
+				sendNotification(func() {
 					sms.SendResolvedNotification(w.service.ID)
-				}()
+				})
 			}
 		}
 	}
@@ -70,9 +125,9 @@ func (w *Watcher) mailNotify(r *Result) {
 		}
 
 		if !w.didReceiveMailNotification || w.didErrorsChange(r) {
-			go func() {
+			sendNotification(func() {
 				mail.SendMails("Error for Service: "+w.service.ID, mail.GenerateErrorMail(errs, "", w.service.ID))
-			}()
+			})
 			w.didReceiveMailNotification = true
 			w.lastErrors = r.Errors
 		}
@@ -84,9 +139,9 @@ func (w *Watcher) mailNotify(r *Result) {
 			w.lastErrors = []Error{}
 
 			if w.service.NotifyIfResolved {
-				go func() {
+				sendNotification(func() {
 					mail.SendMails("Issues resolved for service: "+w.service.ID, mail.GenerateResolvedNotificationMail(w.service.ID))
-				}()
+				})
 			}
 		}
 	}
@@ -111,9 +166,9 @@ func (w *Watcher) slackNotify(r *Result) {
 			}
 		}
 		if !w.didReceiveSlackNotification || w.didErrorsChange(r) {
-			go func() {
+			sendNotification(func() {
 				slack.Send(slack.GenerateErrorMessage(errs, w.service.ID))
-			}()
+			})
 			w.didReceiveSlackNotification = true
 			w.lastErrors = r.Errors
 		}
@@ -125,9 +180,9 @@ func (w *Watcher) slackNotify(r *Result) {
 			w.lastErrors = []Error{}
 
 			if w.service.NotifyIfResolved {
-				go func() {
+				sendNotification(func() {
 					slack.Send(slack.GenerateResolvedNotification(w.service.ID))
-				}()
+				})
 			}
 		}
 	}
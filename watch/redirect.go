@@ -0,0 +1,102 @@
+package watch
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// defaultMaxRedirects limits the hops of calls, that follow redirects without maxRedirects
+const defaultMaxRedirects = 10
+
+// RedirectError stops following redirects
+type RedirectError struct {
+	Type ErrorType
+	URL  string
+	Hops int
+}
+
+func (e *RedirectError) Error() string {
+	if e.Type == ErrorTypeRedirectLoop {
+		return fmt.Sprint("redirect loop after ", e.Hops, " redirects: ", e.URL, " was requested twice before")
+	}
+	return fmt.Sprint("stopped after ", e.Hops, " redirects at ", e.URL)
+}
+
+// followingClient follows redirects with the transport and the cookies of client
+func followingClient(client *http.Client, maxRedirects int) *http.Client {
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+	following := *client
+	following.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		// a URL may be requested again, like a login, that sets a cookie and redirects back - repeating that is a loop
+		requested := 0
+		for _, previous := range via {
+			if previous.URL.String() == req.URL.String() {
+				requested++
+			}
+		}
+		if requested > 1 {
+			return &RedirectError{Type: ErrorTypeRedirectLoop, URL: req.URL.String(), Hops: len(via)}
+		}
+		if len(via) > maxRedirects {
+			return &RedirectError{Type: ErrorTypeTooManyRedirects, URL: req.URL.String(), Hops: len(via) - 1}
+		}
+		return nil
+	}
+	return &following
+}
+
+// redirectHops lists the responses of a redirect chain, the final response is the last hop
+func redirectHops(response *http.Response) (hops []*http.Response) {
+	for hop := response; hop != nil; {
+		hops = append([]*http.Response{hop}, hops...)
+		if hop.Request == nil {
+			break
+		}
+		hop = hop.Request.Response
+	}
+	return hops
+}
+
+func ValidateRedirectChain(ctx *CheckContext) (errs []Error) {
+	if len(ctx.check.RedirectChain) == 0 {
+		return
+	}
+	hops := redirectHops(ctx.response)
+	if len(hops) != len(ctx.check.RedirectChain) {
+		urls := []string{}
+		for _, hop := range hops {
+			urls = append(urls, hop.Request.URL.String())
+		}
+		errs = append(errs, Error{
+			Error:    fmt.Sprint(ctx.call.URL, ": unexpected number of redirect hops: got ", len(hops), " ", urls, ", expected: ", len(ctx.check.RedirectChain)),
+			Type:     ErrorTypeRedirectMismatch,
+			Comment:  ctx.call.Comment,
+			Location: ".redirectChain",
+		})
+		return
+	}
+	for i, expected := range ctx.check.RedirectChain {
+		hop := hops[i]
+		location := ".redirectChain[" + strconv.Itoa(i) + "]"
+		if expected.StatusCode != 0 && int64(hop.StatusCode) != expected.StatusCode {
+			errs = append(errs, Error{
+				Error:    ctx.call.URL + ": unexpected status code of redirect hop " + hop.Request.URL.String() + ": got " + hop.Status + ", expected: " + strconv.FormatInt(expected.StatusCode, 10),
+				Type:     ErrorTypeRedirectMismatch,
+				Comment:  ctx.call.Comment,
+				Location: location,
+			})
+		}
+		if expected.URL != "" && hop.Request.URL.String() != expected.URL {
+			errs = append(errs, Error{
+				Error:    ctx.call.URL + ": unexpected URL of redirect hop: got " + hop.Request.URL.String() + ", expected: " + expected.URL,
+				Type:     ErrorTypeRedirectMismatch,
+				Comment:  ctx.call.Comment,
+				Location: location,
+			})
+		}
+	}
+	return
+}
@@ -0,0 +1,91 @@
+package watch
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/foomo/petze/config"
+)
+
+func TestRunSessionFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case "/loop-a":
+			http.Redirect(w, r, "/loop-b", http.StatusFound)
+		case "/loop-b":
+			http.Redirect(w, r, "/loop-a", http.StatusFound)
+		case "/account":
+			if _, errCookie := r.Cookie("session"); errCookie != nil {
+				http.Redirect(w, r, "/login", http.StatusFound)
+				return
+			}
+			w.Write([]byte("account"))
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1", Path: "/"})
+			http.Redirect(w, r, "/account", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	run := func(call config.Call) []Error {
+		w := NewWatcher(&config.Service{ID: "redirects", Endpoint: server.URL, Session: []config.Call{call}})
+		client, _ := w.getClientAndDialErrRecorder()
+		client.Jar, _ = cookiejar.New(nil)
+		r := NewResult("redirects")
+		if err := w.runSession(context.Background(), r, client); err != nil {
+			t.Fatal(err)
+		}
+		return r.Errors
+	}
+
+	chain := []config.RedirectHop{
+		{StatusCode: 301, URL: server.URL + "/old"},
+		{StatusCode: 302, URL: server.URL + "/new"},
+		{StatusCode: 200, URL: server.URL + "/final"},
+	}
+	if errs := run(config.Call{URI: "/old", FollowRedirects: true, Check: []config.Check{
+		{StatusCode: 200, Redirect: server.URL + "/final"},
+		{RedirectChain: chain},
+	}}); len(errs) != 0 {
+		t.Error("checks have to apply to the final response", errs)
+	}
+
+	chain[1].StatusCode = 307
+	if errs := run(config.Call{URI: "/old", FollowRedirects: true, Check: []config.Check{{RedirectChain: chain}}}); len(errs) != 1 ||
+		errs[0].Type != ErrorTypeRedirectMismatch || errs[0].Location != "@call[0].check[0].redirectChain[1]" {
+		t.Error("expected a mismatch of the second hop", errs)
+	}
+
+	if errs := run(config.Call{URI: "/loop-a", FollowRedirects: true}); len(errs) != 1 || errs[0].Type != ErrorTypeRedirectLoop || errs[0].Location != "@call[0]" {
+		t.Error("expected a redirect loop", errs)
+	}
+
+	// coming back to a URL once with a cookie is not a loop
+	if errs := run(config.Call{URI: "/account", FollowRedirects: true, Check: []config.Check{
+		{StatusCode: 200, Redirect: server.URL + "/account", RedirectChain: []config.RedirectHop{
+			{StatusCode: 302, URL: server.URL + "/account"},
+			{StatusCode: 302, URL: server.URL + "/login"},
+			{StatusCode: 200, URL: server.URL + "/account"},
+		}},
+	}}); len(errs) != 0 {
+		t.Error("expected the login redirect to be followed", errs)
+	}
+
+	if errs := run(config.Call{URI: "/old", FollowRedirects: true, MaxRedirects: 1}); len(errs) != 1 || errs[0].Type != ErrorTypeTooManyRedirects {
+		t.Error("expected too many redirects", errs)
+	}
+
+	// without followRedirects the first response is checked
+	if errs := run(config.Call{URI: "/old", Check: []config.Check{{StatusCode: 301, Redirect: server.URL + "/new"}}}); len(errs) != 0 {
+		t.Error("redirects must not be followed by default", errs)
+	}
+}
@@ -0,0 +1,37 @@
+package watch
+
+import (
+	"context"
+	"time"
+
+	"github.com/foomo/petze/config"
+)
+
+// Scheduler decides when the sessions of a watcher run
+type Scheduler interface {
+	// Offset of the runs within the interval of the service - the first session runs immediately
+	Offset(service *config.Service) time.Duration
+	// Acquire blocks until a session may run, release has to be called when it is done
+	Acquire(ctx context.Context, service *config.Service) (release func(), err error)
+}
+
+// unscheduled watchers start immediately and run without limits
+type unscheduled struct{}
+
+func (unscheduled) Offset(service *config.Service) time.Duration {
+	return 0
+}
+
+func (unscheduled) Acquire(ctx context.Context, service *config.Service) (release func(), err error) {
+	return func() {}, nil
+}
+
+// nextRun keeps the runs at a fixed rate - runs, that were missed while a session took too long, are skipped
+func nextRun(scheduled time.Time, interval time.Duration, now time.Time) time.Time {
+	next := scheduled.Add(interval)
+	if interval > 0 && next.Before(now) {
+		missed := now.Sub(next) / interval
+		next = next.Add((missed + 1) * interval)
+	}
+	return next
+}
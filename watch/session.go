@@ -1,6 +1,7 @@
 package watch
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -9,8 +10,6 @@ import (
 
 	"io"
 
-	"encoding/json"
-
 	"bytes"
 
 	"io/ioutil"
@@ -24,7 +23,7 @@ func SetUserAgentVersion(version string) {
 	userAgent += "/"+version
 }
 
-func (w *Watcher) runSession(r *Result, client *http.Client) error {
+func (w *Watcher) runSession(ctx context.Context, r *Result, client *http.Client) error {
 
 	//log.Println("running session with session length:", len(service.Session))
 	//spew.Dump(service)
@@ -33,89 +32,199 @@ func (w *Watcher) runSession(r *Result, client *http.Client) error {
 	if errURL != nil {
 		return errors.New("can not run session: " + errURL.Error())
 	}
+	if w.errOpenAPI != nil {
+		r.addError(w.errOpenAPI, ErrorOpenAPI, "")
+	}
+	vars := sessionVars{}
 	for indexCall, call := range w.service.Session {
+		location := fmt.Sprint("@call[", indexCall, "]")
+		if len(call.ForEach) == 0 {
+			stop, errCall := w.runCall(ctx, r, client, endPointURL, vars, call, location)
+			if errCall != nil || stop {
+				return errCall
+			}
+			continue
+		}
 
-		// copy URL
-		callURL := &url.URL{}
-		*callURL = *endPointURL
-
-		uriURL, errURIURL := call.GetURL()
-		if errURIURL != nil {
-			return errURIURL
+		// the call is repeated for each value, that is available as a variable
+		name := call.ForEachVar()
+		previous, hadPrevious := vars[name]
+		for _, value := range call.ForEach {
+			vars[name] = value
+			stop, errCall := w.runCall(ctx, r, client, endPointURL, vars, call, location+"["+name+"="+value+"]")
+			if errCall != nil || stop {
+				return errCall
+			}
+		}
+		if hadPrevious {
+			vars[name] = previous
+		} else {
+			delete(vars, name)
 		}
+	}
+	return nil
+}
 
-		callURL.Path = uriURL.Path
-		callURL.RawQuery = uriURL.RawQuery
+// runCall runs a call and adds the errors of its checks to r - stop is true, when the following calls can not run
+func (w *Watcher) runCall(ctx context.Context, r *Result, client *http.Client, endPointURL *url.URL, vars sessionVars, call config.Call, location string) (stop bool, err error) {
 
-		// overwrite scheme if desired
-		if call.Scheme != "" {
-			callURL.Scheme = call.Scheme
-		}
+	// use the variables extracted by previous calls
+	call, errRender := vars.renderCall(call)
+	if errRender != nil {
+		return true, errors.New(location + ": " + errRender.Error())
+	}
+
+	callURL, errCallURL := getCallURL(endPointURL, call)
+	if errCallURL != nil {
+		return true, errCallURL
+	}
+	call.URL = callURL.String()
+
+	method := http.MethodGet
+	if call.Method != "" {
+		method = call.Method
+	}
+	body, contentType, errBody := requestBody(call)
+	if errBody != nil {
+		return true, errBody
+	}
+
+	// the call has to finish within its own timeout and the one of the session
+	timeout := callTimeout(call)
+	callCtx, cancelCall := context.WithTimeout(ctx, timeout)
+	defer cancelCall()
+
+	req, errNewRequest := http.NewRequestWithContext(callCtx, method, callURL.String(), body)
+	if errNewRequest != nil {
+		return true, errNewRequest
+	}
+	start := time.Now()
+
+	// set default user agent first, so it can be overwritten via the custom header fields if desired
+	req.Header.Set("User-Agent", userAgent)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 
-		call.URL = callURL.String()
+	// set the HTTP header fields specified for the call
+	for k, v := range call.Headers {
+		req.Header.Set(k, v)
+	}
 
-		var body io.Reader
-		method := http.MethodGet
-		if call.Method != "" {
-			method = call.Method
+	// execute the HTTP request
+	callClient := client
+	if call.FollowRedirects {
+		callClient = followingClient(client, call.MaxRedirects)
+	}
+	response, errResponse := callClient.Do(req)
+	if errResponse != nil {
+		var errRedirect *RedirectError
+		if errors.As(errResponse, &errRedirect) {
+			// there is no final response to check
+			r.Errors = append(r.Errors, Error{
+				Error:    call.URL + ": " + errRedirect.Error(),
+				Type:     errRedirect.Type,
+				Comment:  call.Comment,
+				Location: location,
+			})
+			return true, nil
 		}
-		if call.Data != nil {
-			dataBytes, errDataBytes := json.Marshal(call.Data)
-			if errDataBytes != nil {
-				return errors.New("could not encode data bytes: " + errDataBytes.Error())
-			}
-			//fmt.Println("JSON body", string(dataBytes))
-			body = bytes.NewBuffer(dataBytes)
+		if errTimeout := w.timeoutError(ctx, callCtx, timeout, call.URL, location); errTimeout != nil {
+			return true, errTimeout
 		}
+		return true, errResponse
+	}
+	defer response.Body.Close()
 
-		req, errNewRequest := http.NewRequest(method, callURL.String(), body)
-		if errNewRequest != nil {
-			return errNewRequest
-		}
-		start := time.Now()
+	// measure time
+	duration := time.Since(start)
 
-		// set default user agent first, so it can be overwritten via the custom header fields if desired
-		req.Header.Set("User-Agent", userAgent)
+	// get reader for response body
+	responseBodyReader, readerErr := getResponseBodyReader(response)
+	if readerErr != nil {
+		if errTimeout := w.timeoutError(ctx, callCtx, timeout, call.URL, location); errTimeout != nil {
+			return true, errTimeout
+		}
+		return true, readerErr
+	}
 
-		// set the HTTP header fields specified for the call
-		for k, v := range call.Headers {
-			//fmt.Println("set header", k, v)
-			req.Header.Set(k, v)
+	// process all checks for the call
+	for indexCheck, chk := range call.Check {
+		ctx := &CheckContext{
+			response:           response,
+			responseBodyReader: responseBodyReader,
+			check:              chk,
+			call:               call,
+			duration:           duration,
 		}
+		for _, newErr := range checkResponse(ctx) {
+			newErr.Location = fmt.Sprint(location, ".check[", indexCheck, "]", newErr.Location)
+			r.Errors = append(r.Errors, newErr)
+		}
+		responseBodyReader.Seek(0, io.SeekStart)
+	}
 
-		// execute the HTTP request
-		response, errResponse := client.Do(req)
-		if errResponse != nil {
-			return errResponse
+	// validate the call against the OpenAPI document - it only describes the endpoint
+	if w.openAPI != nil && callURL.Host == endPointURL.Host {
+		ctx := &CheckContext{
+			response:           response,
+			responseBodyReader: responseBodyReader,
+			call:               call,
+			duration:           duration,
+		}
+		for _, newErr := range ValidateOpenAPI(w.openAPI, ctx) {
+			newErr.Location = location + ".openAPI" + newErr.Location
+			r.Errors = append(r.Errors, newErr)
 		}
-		defer response.Body.Close()
+		responseBodyReader.Seek(0, io.SeekStart)
+	}
 
-		// measure time
-		duration := time.Since(start)
+	// capture variables for the following calls
+	checkCtx := &CheckContext{
+		response:           response,
+		responseBodyReader: responseBodyReader,
+		call:               call,
+		duration:           duration,
+	}
+	errsExtract := vars.extract(checkCtx, responseBodyReader)
+	for _, newErr := range errsExtract {
+		newErr.Location = location + newErr.Location
+		r.Errors = append(r.Errors, newErr)
+	}
+	// the following calls depend on the missing variables
+	return len(errsExtract) > 0, nil
+}
 
-		// get reader for response body
-		responseBodyReader, readerErr := getResponseBodyReader(response)
-		if readerErr != nil {
-			return readerErr
+// getCallURL resolves the uri of a call against the endpoint - absolute uris and the host of a call target other hosts
+func getCallURL(endPointURL *url.URL, call config.Call) (*url.URL, error) {
+	uriURL, errURIURL := call.GetURL()
+	if errURIURL != nil {
+		return nil, errURIURL
+	}
+	callURL := &url.URL{}
+	if uriURL.Host != "" {
+		// absolute or scheme relative like //cdn.example.com/app.js
+		*callURL = *uriURL
+		if callURL.Scheme == "" {
+			callURL.Scheme = endPointURL.Scheme
 		}
+	} else {
+		// copy URL
+		*callURL = *endPointURL
+		callURL.Path = uriURL.Path
+		callURL.RawPath = uriURL.RawPath
+		callURL.RawQuery = uriURL.RawQuery
+		callURL.Fragment = uriURL.Fragment
+	}
 
-		// process all checks for the call
-		for indexCheck, chk := range call.Check {
-			ctx := &CheckContext{
-				response:           response,
-				responseBodyReader: responseBodyReader,
-				check:              chk,
-				call:               call,
-				duration:           duration,
-			}
-			for _, newErr := range checkResponse(ctx) {
-				newErr.Location = fmt.Sprint("@call[", indexCall, "].check[", indexCheck, "]")
-				r.Errors = append(r.Errors, newErr)
-			}
-			responseBodyReader.Seek(0, io.SeekStart)
-		}
+	// overwrite host and scheme if desired
+	if call.Host != "" {
+		callURL.Host = call.Host
 	}
-	return nil
+	if call.Scheme != "" {
+		callURL.Scheme = call.Scheme
+	}
+	return callURL, nil
 }
 
 func getResponseBodyReader(response *http.Response) (io.ReadSeeker, error) {
@@ -136,10 +245,13 @@ type CheckContext struct {
 
 var ContextValidators = []ValidatorFunc{
 	ValidateRedirects,
+	ValidateRedirectChain,
 	ValidateHeaders,
 	ValidateStatusCode,
 	ValidateJsonPath,
+	ValidateJSONSchema,
 	ValidateGoQuery,
+	ValidateXPath,
 	ValidateDuration,
 	ValidateContentType,
 	ValidateRegex,
@@ -150,6 +262,10 @@ func checkResponse(ctx *CheckContext) []Error {
 	errs := []Error{}
 
 	for _, validator := range ContextValidators {
+		// every validator reads the body from the start
+		if seeker, ok := ctx.responseBodyReader.(io.Seeker); ok {
+			seeker.Seek(0, io.SeekStart)
+		}
 		errs = append(errs, validator(ctx)...)
 	}
 
@@ -0,0 +1,272 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/foomo/petze/config"
+)
+
+func TestRunSessionOpenAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "one"}`))
+	}))
+	defer server.Close()
+
+	w := NewWatcher(&config.Service{
+		ID:       "openapi",
+		Endpoint: server.URL,
+		Session:  []config.Call{{URI: "/pets/1"}},
+		OpenAPI: &config.OpenAPI{Document: []byte(`
+openapi: 3.0.0
+info: {title: pets, version: "1"}
+paths:
+  /pets/{id}:
+    get:
+      responses:
+        "200":
+          description: a pet
+          content:
+            application/json:
+              schema:
+                properties:
+                  id: {type: integer}
+`)},
+	})
+	r := NewResult("openapi")
+	if err := w.runSession(context.Background(), r, server.Client()); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 1 || r.Errors[0].Type != ErrorOpenAPI || r.Errors[0].Location != "@call[0].openAPI#/id" {
+		t.Error("expected one openAPI violation", r.Errors)
+	}
+}
+
+func TestRunSessionVars(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if r.Method == http.MethodPost {
+				var login map[string]string
+				json.NewDecoder(r.Body).Decode(&login)
+				if login["csrf"] != "t0k3n" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.Header().Set("X-Session", "s1")
+				w.Write([]byte(`{"order": {"id": 42, "name": "first"}}`))
+				return
+			}
+			w.Write([]byte(`<form><input name="csrf" value="t0k3n"></form>`))
+		case "/orders/42":
+			if r.Header.Get("X-Session") != "s1" || r.URL.Query().Get("name") != "first" {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}
+	}))
+	defer server.Close()
+
+	w := NewWatcher(&config.Service{
+		ID:       "vars",
+		Endpoint: server.URL,
+		Session: []config.Call{
+			{URI: "/login", Extract: map[string]config.Extract{"csrf": {GoQuery: "input[name=csrf]@value"}}},
+			{
+				URI:    "/login",
+				Method: http.MethodPost,
+				Data:   map[string]interface{}{"csrf": "{{ .vars.csrf }}"},
+				Check:  []config.Check{{StatusCode: 200}},
+				Extract: map[string]config.Extract{
+					"orderID": {JSONPath: "$.order.id+"},
+					"name":    {Regex: `"name": "([a-z]+)"`},
+					"session": {Header: "x-session"},
+				},
+			},
+			{
+				URI:     "/orders/{{ .vars.orderID }}?name={{ .vars.name }}",
+				Headers: map[string]string{"X-Session": "{{ .vars.session }}"},
+				Check:   []config.Check{{StatusCode: 200}},
+			},
+		},
+	})
+	r := NewResult("vars")
+	if err := w.runSession(context.Background(), r, server.Client()); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 0 {
+		t.Error("unexpected errors", r.Errors)
+	}
+
+	// a failed extraction stops the session
+	w.service.Session[0].Extract["csrf"] = config.Extract{GoQuery: "input[name=missing]@value"}
+	r = NewResult("vars")
+	if err := w.runSession(context.Background(), r, server.Client()); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 1 || r.Errors[0].Type != ErrorExtract || r.Errors[0].Location != "@call[0].extract.csrf" {
+		t.Error("expected one extract error", r.Errors)
+	}
+
+	// undefined variables fail the session
+	w.service.Session = w.service.Session[2:]
+	if err := w.runSession(context.Background(), NewResult("vars"), server.Client()); err == nil {
+		t.Error("expected an error for an undefined variable")
+	}
+}
+
+func TestWatchTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hang" {
+			// accept the request and never answer
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name             string
+		service          *config.Service
+		expectedError    string
+		expectedLocation string
+	}{
+		{
+			name: "call timeout",
+			service: &config.Service{
+				Session: []config.Call{{URI: "/"}, {URI: "/hang", Timeout: 50 * time.Millisecond}},
+			},
+			expectedError:    server.URL + "/hang: call timed out after 50ms",
+			expectedLocation: "@call[1]",
+		},
+		{
+			name: "session timeout",
+			service: &config.Service{
+				SessionTimeout: 50 * time.Millisecond,
+				Session:        []config.Call{{URI: "/hang"}},
+			},
+			expectedError:    server.URL + "/hang: session timed out after 50ms",
+			expectedLocation: "@call[0]",
+		},
+	}
+	for _, test := range tests {
+		test.service.ID = "timeout"
+		test.service.Endpoint = server.URL
+		w := NewWatcher(test.service)
+		client, errRecorder := w.getClientAndDialErrRecorder()
+		start := time.Now()
+		r := w.watch(client, errRecorder)
+		if time.Since(start) > time.Second {
+			t.Error(test.name, "the timeout was not enforced")
+		}
+		if !r.Timeout || len(r.Errors) != 1 || r.Errors[0].Type != ErrorTypeTimeout ||
+			r.Errors[0].Error != test.expectedError || r.Errors[0].Location != test.expectedLocation {
+			t.Error(test.name, "expected one timeout error", r.Timeout, r.Errors)
+		}
+	}
+}
+
+func TestRunSessionForEach(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/products/sku-2" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	w := NewWatcher(&config.Service{
+		ID:       "forEach",
+		Endpoint: server.URL,
+		Session: []config.Call{
+			{
+				URI:     "/products/{{ .vars.sku }}",
+				ForEach: []string{"sku-1", "sku-2", "sku-3"},
+				As:      "sku",
+				Check:   []config.Check{{StatusCode: 200}},
+			},
+			{
+				URI:     "/{{ .vars.item }}/",
+				ForEach: []string{"de", "fr"},
+				Check:   []config.Check{{StatusCode: 200}},
+			},
+		},
+	})
+	r := NewResult("forEach")
+	if err := w.runSession(context.Background(), r, server.Client()); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 1 || r.Errors[0].Location != "@call[0][sku=sku-2].check[0]" {
+		t.Error("expected one error for the failing value", r.Errors)
+	}
+
+	// the value is only available to the repeated call
+	w.service.Session = append(w.service.Session, config.Call{URI: "/{{ .vars.sku }}"})
+	if err := w.runSession(context.Background(), NewResult("forEach"), server.Client()); err == nil {
+		t.Error("expected an error for the variable of a finished loop")
+	}
+}
+
+func TestGetCallURL(t *testing.T) {
+	endPointURL, _ := url.Parse("https://shop.example.com")
+	tests := []struct {
+		call     config.Call
+		expected string
+	}{
+		{call: config.Call{URI: "/cart?id=1#top"}, expected: "https://shop.example.com/cart?id=1#top"},
+		{call: config.Call{URI: "https://auth.example.com:8443/login?next=%2F"}, expected: "https://auth.example.com:8443/login?next=%2F"},
+		{call: config.Call{URI: "//cdn.example.com/app.js"}, expected: "https://cdn.example.com/app.js"},
+		{call: config.Call{URI: "/login", Host: "auth.example.com:8443"}, expected: "https://auth.example.com:8443/login"},
+		{call: config.Call{URI: "/health", Scheme: "http"}, expected: "http://shop.example.com/health"},
+	}
+	for _, test := range tests {
+		callURL, err := getCallURL(endPointURL, test.call)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if callURL.String() != test.expected {
+			t.Error("unexpected url for", test.call.URI, ": got", callURL.String(), "expected", test.expected)
+		}
+	}
+}
+
+func TestRunSessionOtherHost(t *testing.T) {
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3ss10n", Path: "/"})
+	}))
+	defer auth.Close()
+	shop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "s3ss10n" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer shop.Close()
+	authURL, _ := url.Parse(auth.URL)
+
+	w := NewWatcher(&config.Service{
+		ID:       "otherHost",
+		Endpoint: shop.URL,
+		Session: []config.Call{
+			{URI: "/login", Host: authURL.Host, Check: []config.Check{{StatusCode: 200}}},
+			{URI: "/account", Check: []config.Check{{StatusCode: 200}}},
+		},
+	})
+	client, _ := w.getClientAndDialErrRecorder()
+	jar, _ := cookiejar.New(nil)
+	client.Jar = jar
+	r := NewResult("otherHost")
+	if err := w.runSession(context.Background(), r, client); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 0 {
+		t.Error("the cookie jar has to be shared with other hosts", r.Errors)
+	}
+}
@@ -0,0 +1,56 @@
+package watch
+
+import (
+	"context"
+	"time"
+
+	"github.com/foomo/petze/config"
+)
+
+// defaultTimeout applies to calls without a timeout and to the endpoint check
+const defaultTimeout = 30 * time.Second
+
+// TimeoutError is reported, when a call or the whole session did not finish in time
+type TimeoutError struct {
+	URL string
+	// the exceeded timeout
+	Timeout time.Duration
+	// the session timeout was exceeded, not the one of a call
+	Session bool
+	// the call, that was running, e.g. @call[1]
+	Location string
+}
+
+func (e *TimeoutError) Error() string {
+	scope := "call"
+	if e.Session {
+		scope = "session"
+	}
+	return e.URL + ": " + scope + " timed out after " + e.Timeout.String()
+}
+
+func callTimeout(call config.Call) time.Duration {
+	if call.Timeout > 0 {
+		return call.Timeout
+	}
+	return defaultTimeout
+}
+
+// sessionContext is cancelled with the watcher and limited by the session timeout of the service, if there is one
+func (w *Watcher) sessionContext() (ctx context.Context, cancel context.CancelFunc) {
+	if w.service.SessionTimeout > 0 {
+		return context.WithTimeout(w.ctx, w.service.SessionTimeout)
+	}
+	return context.WithCancel(w.ctx)
+}
+
+// timeoutError tells, if a request failed, because the session or the call exceeded its deadline
+func (w *Watcher) timeoutError(sessionCtx, callCtx context.Context, timeout time.Duration, url, location string) *TimeoutError {
+	switch {
+	case sessionCtx.Err() == context.DeadlineExceeded:
+		return &TimeoutError{URL: url, Timeout: w.service.SessionTimeout, Session: true, Location: location}
+	case callCtx.Err() == context.DeadlineExceeded:
+		return &TimeoutError{URL: url, Timeout: timeout, Location: location}
+	}
+	return nil
+}
@@ -3,6 +3,9 @@ package watch
 import (
 	"fmt"
 	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -14,6 +17,17 @@ import (
 type ValidatorFunc func(ctx *CheckContext) (errs []Error)
 
 func ValidateRedirects(ctx *CheckContext) (errs []Error) {
+	if len(ctx.check.Redirect) > 0 && ctx.call.FollowRedirects {
+		// the final URL of the followed redirects
+		if finalURL := ctx.response.Request.URL.String(); finalURL != ctx.check.Redirect {
+			errs = append(errs, Error{
+				Error:   ctx.call.URL + ": unexpected redirect URL: got " + finalURL + ", expected: " + ctx.check.Redirect,
+				Type:    ErrorTypeRedirectMismatch,
+				Comment: ctx.call.Comment,
+			})
+		}
+		return
+	}
 	if len(ctx.check.Redirect) > 0 {
 		url, err := ctx.response.Location()
 		if err == nil {
@@ -30,12 +44,18 @@ func ValidateRedirects(ctx *CheckContext) (errs []Error) {
 }
 
 func ValidateHeaders(ctx *CheckContext) (errs []Error) {
-	for k, v := range ctx.check.Headers {
-		if ctx.response.Header.Get(k) != v {
+	for name, expect := range ctx.check.Headers {
+		header := ctx.response.Header
+		if key := http.CanonicalHeaderKey(name); expect.Plain && len(header[key]) == 0 {
+			// a plain string compares a missing header as an empty string
+			header = http.Header{key: {""}}
+		}
+		if ok, info := check.Header(header, name, expect.Expect); !ok {
 			errs = append(errs, Error{
-				Error:   ctx.call.URL + ": unexpected value for HTTP header " + k + ": got " + ctx.response.Header.Get(k) + ", expected: " + k,
-				Type:    ErrorTypeHeaderMismatch,
-				Comment: ctx.call.Comment,
+				Error:    ctx.call.URL + ": unexpected value for HTTP header " + name + ": " + info,
+				Type:     ErrorTypeHeaderMismatch,
+				Comment:  ctx.call.Comment,
+				Location: ".headers." + name,
 			})
 		}
 	}
@@ -56,12 +76,10 @@ func ValidateStatusCode(ctx *CheckContext) (errs []Error) {
 
 func ValidateJsonPath(ctx *CheckContext) (errs []Error) {
 	if ctx.check.JSONPath != nil {
+		// the contentType of the call is the one of the request body
 		contentType := config.ContentTypeJSON
-		if ctx.call.ContentType != "" {
-			contentType = ctx.call.ContentType
-		}
 		if ctx.check.ContentType != "" {
-			contentType = ctx.check.ContentType
+			contentType, _, _ = mime.ParseMediaType(ctx.check.ContentType)
 		}
 
 		dataBytes, errDataBytes := ioutil.ReadAll(ctx.responseBodyReader)
@@ -83,7 +101,7 @@ func ValidateJsonPath(ctx *CheckContext) (errs []Error) {
 				}
 			default:
 				errs = append(errs, Error{
-					Error:   ctx.call.URL + ": data contentType: " + contentType + " is not supported (yet?)",
+					Error:   ctx.call.URL + ": data contentType: " + contentType + " is not supported, use xPath for xml and html",
 					Type:    ErrorTypeNotImplemented,
 					Comment: ctx.call.Comment,
 				})
@@ -93,6 +111,68 @@ func ValidateJsonPath(ctx *CheckContext) (errs []Error) {
 	return
 }
 
+// ValidateJSONSchema reports each schema violation as an error with its JSON pointer in the location
+func ValidateJSONSchema(ctx *CheckContext) (errs []Error) {
+	if ctx.check.JSONSchema != nil {
+		dataBytes, errDataBytes := ioutil.ReadAll(ctx.responseBodyReader)
+		if errDataBytes != nil {
+			errs = append(errs, Error{Error: ctx.call.URL + ": could not read data from response: " + errDataBytes.Error(), Comment: ctx.call.Comment})
+			return
+		}
+		violations, errValidate := check.JSONSchema(dataBytes, ctx.check.JSONSchema.JSONPath, ctx.check.JSONSchema.Schema)
+		if errValidate != nil {
+			errs = append(errs, Error{
+				Error:   ctx.call.URL + ": " + errValidate.Error(),
+				Type:    ErrorJSONSchema,
+				Comment: ctx.call.Comment,
+			})
+			return
+		}
+		for _, violation := range violations {
+			errs = append(errs, Error{
+				Error:    ctx.call.URL + ": " + ctx.check.JSONSchema.JSONPath + "#" + violation.Pointer + ": " + violation.Description,
+				Type:     ErrorJSONSchema,
+				Comment:  ctx.call.Comment,
+				Location: "#" + violation.Pointer,
+			})
+		}
+	}
+	return
+}
+
+// ValidateOpenAPI validates status code, content type and body against the operation documented for the call
+func ValidateOpenAPI(doc *check.OpenAPIDocument, ctx *CheckContext) (errs []Error) {
+	dataBytes, errDataBytes := ioutil.ReadAll(ctx.responseBodyReader)
+	if errDataBytes != nil {
+		errs = append(errs, Error{Error: ctx.call.URL + ": could not read data from response: " + errDataBytes.Error(), Comment: ctx.call.Comment})
+		return
+	}
+	method := http.MethodGet
+	if ctx.call.Method != "" {
+		method = ctx.call.Method
+	}
+	path := ""
+	if ctx.response.Request != nil {
+		path = ctx.response.Request.URL.Path
+	} else if callURL, errURL := url.Parse(ctx.call.URL); errURL == nil {
+		path = callURL.Path
+	}
+	for _, violation := range doc.ValidateResponse(method, path, ctx.response, dataBytes) {
+		location, message := "", violation.Description
+		if violation.Pointer != "" {
+			location = "#" + violation.Pointer
+			message = location + ": " + message
+		}
+		errs = append(errs, Error{
+			Error:    ctx.call.URL + ": " + message,
+			Type:     ErrorOpenAPI,
+			Comment:  ctx.call.Comment,
+			Location: location,
+		})
+	}
+	return
+}
+
 func ValidateDuration(ctx *CheckContext) (errs []Error) {
 	if ctx.check.Duration > 0 {
 		if ctx.duration > ctx.check.Duration {
@@ -133,10 +213,43 @@ func ValidateGoQuery(ctx *CheckContext) (errs []Error) {
 	return
 }
 
+func ValidateXPath(ctx *CheckContext) (errs []Error) {
+	if ctx.check.XPath != nil {
+
+		// html is parsed leniently, everything else as xml
+		newDocument := check.NewXMLDocument
+		mediaType, _, _ := mime.ParseMediaType(ctx.response.Header.Get("Content-Type"))
+		if mediaType == "text/html" {
+			newDocument = check.NewHTMLDocument
+		}
+
+		doc, errDoc := newDocument(ctx.responseBodyReader)
+		if errDoc != nil {
+			errs = append(errs, Error{
+				Error:   ctx.call.URL + ": could not parse " + mediaType + " document: " + errDoc.Error(),
+				Type:    ErrorXPath,
+				Comment: ctx.call.Comment,
+			})
+			return
+		}
+		for selector, expect := range ctx.check.XPath {
+			ok, info := check.XPath(doc, selector, expect)
+			if !ok {
+				errs = append(errs, Error{
+					Error:   ctx.call.URL + ": " + selector + ": " + info,
+					Type:    ErrorXPath,
+					Comment: ctx.call.Comment,
+				})
+			}
+		}
+	}
+	return
+}
+
 func ValidateContentType(ctx *CheckContext) (errs []Error) {
 	if ctx.check.ContentType != "" {
 		contentType := ctx.response.Header.Get("Content-Type")
-		if contentType != ctx.check.ContentType {
+		if !check.MediaTypeMatches(contentType, ctx.check.ContentType) {
 			errs = append(errs, Error{
 				Error:   ctx.call.URL + ": unexpected Content-Type: \"" + contentType + "\", expected: \"" + ctx.check.ContentType + "\"",
 				Type:    ErrorTypeUnexpectedContentType,
@@ -5,6 +5,7 @@ import (
 	"github.com/foomo/petze/config"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -78,3 +79,103 @@ func TestValidateJsonPath(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateXPath(t *testing.T) {
+	data := `<rss><channel><item><title>one</title></item></channel></rss>`
+	ctx := &CheckContext{
+		responseBodyReader: bytes.NewReader([]byte(data)),
+		response:           createResponse(data, "application/rss+xml"),
+		check: config.Check{XPath: map[string]config.Expect{
+			"//item":       {Min: &[]int64{1}[0]},
+			"//item/title": {Equals: "two"},
+		}},
+	}
+	errs := ValidateXPath(ctx)
+	if len(errs) != 1 || errs[0].Type != ErrorXPath {
+		t.Error("expected exactly one xpath error", errs)
+	}
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	data := `{"products": [{"sku": "a"}, {"sku": 2}]}`
+	ctx := &CheckContext{
+		responseBodyReader: bytes.NewReader([]byte(data)),
+		response:           createResponse(data, "application/json"),
+		check: config.Check{JSONSchema: &config.JSONSchema{
+			JSONPath: "$.products+",
+			Schema: map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"properties": map[string]interface{}{"sku": map[string]interface{}{"type": "string"}}},
+			},
+		}},
+	}
+	errs := ValidateJSONSchema(ctx)
+	if len(errs) != 1 || errs[0].Type != ErrorJSONSchema || errs[0].Location != "#/1/sku" {
+		t.Error("expected one violation with a json pointer", errs)
+	}
+}
+
+func TestCheckResponseJSONPathAndSchema(t *testing.T) {
+	data := `{"products": [{"sku": "a"}, {"sku": 2}]}`
+	ctx := &CheckContext{
+		responseBodyReader: bytes.NewReader([]byte(data)),
+		response:           createResponse(data, "application/json"),
+		check: config.Check{
+			JSONPath: map[string]config.Expect{"$.products+": {Count: &[]int64{2}[0]}},
+			JSONSchema: &config.JSONSchema{
+				JSONPath: "$.products+",
+				Schema: map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"properties": map[string]interface{}{"sku": map[string]interface{}{"type": "string"}}},
+				},
+			},
+		},
+	}
+	errs := checkResponse(ctx)
+	if len(errs) != 1 || errs[0].Type != ErrorJSONSchema || errs[0].Location != "#/1/sku" {
+		t.Error("expected only the schema violation", errs)
+	}
+}
+
+func TestCheckResponseGoQueryAndXPath(t *testing.T) {
+	data := `<html><body><h1>hello</h1><p class="teaser">one</p></body></html>`
+	ctx := &CheckContext{
+		responseBodyReader: bytes.NewReader([]byte(data)),
+		response:           createResponse(data, "text/html"),
+		check: config.Check{
+			GoQuery: map[string]config.Expect{"h1": {Equals: "hello"}},
+			XPath: map[string]config.Expect{
+				"//h1":                 {Equals: "hello"},
+				"//p[@class='teaser']": {Absent: true},
+				"count(//p)":           {Lte: &[]float64{1}[0]},
+			},
+		},
+	}
+	errs := checkResponse(ctx)
+	if len(errs) != 1 || errs[0].Type != ErrorXPath || !strings.Contains(errs[0].Error, "teaser") {
+		t.Error("expected only the absent teaser to fail", errs)
+	}
+}
+
+func TestValidateHeadersPlain(t *testing.T) {
+	response := createResponse("", "text/plain")
+	tests := []struct {
+		expect config.HeaderExpect
+		length int
+	}{
+		// like http.Header.Get a plain string compares a missing header as an empty string
+		{config.PlainHeaderExpect(""), 0},
+		{config.PlainHeaderExpect("foo"), 1},
+		{config.HeaderExpect{Expect: config.Expect{Equals: ""}}, 1},
+		{config.HeaderExpect{Expect: config.Expect{Absent: true}}, 0},
+	}
+	for _, test := range tests {
+		ctx := &CheckContext{
+			response: response,
+			check:    config.Check{Headers: map[string]config.HeaderExpect{"X-Missing": test.expect}},
+		}
+		if errs := ValidateHeaders(ctx); len(errs) != test.length {
+			t.Error("unexpected header validation", test.expect, errs)
+		}
+	}
+}
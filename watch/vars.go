@@ -0,0 +1,132 @@
+package watch
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/foomo/petze/check"
+	"github.com/foomo/petze/config"
+)
+
+// sessionVars are extracted from responses and used in the following calls of a session
+type sessionVars map[string]string
+
+// render executes a template like /orders/{{ .vars.orderID }} - strings without actions are returned as they are
+func (vars sessionVars) render(text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, errParse := template.New("").Option("missingkey=error").Parse(text)
+	if errParse != nil {
+		return "", errors.New("could not parse template " + text + " : " + errParse.Error())
+	}
+	buf := &bytes.Buffer{}
+	errExecute := tmpl.Execute(buf, map[string]interface{}{"vars": map[string]string(vars)})
+	if errExecute != nil {
+		return "", errors.New("could not render template " + text + " : " + errExecute.Error())
+	}
+	return buf.String(), nil
+}
+
+// renderData renders all strings in call data
+func (vars sessionVars) renderData(data interface{}) (rendered interface{}, err error) {
+	switch d := data.(type) {
+	case string:
+		return vars.render(d)
+	case map[string]interface{}:
+		renderedMap := make(map[string]interface{}, len(d))
+		for key, value := range d {
+			renderedValue, errRender := vars.renderData(value)
+			if errRender != nil {
+				return nil, errRender
+			}
+			renderedMap[key] = renderedValue
+		}
+		return renderedMap, nil
+	case []interface{}:
+		renderedSlice := make([]interface{}, len(d))
+		for i, value := range d {
+			renderedValue, errRender := vars.renderData(value)
+			if errRender != nil {
+				return nil, errRender
+			}
+			renderedSlice[i] = renderedValue
+		}
+		return renderedSlice, nil
+	default:
+		return data, nil
+	}
+}
+
+// renderCall renders uri, host, headers and data of a call
+func (vars sessionVars) renderCall(call config.Call) (rendered config.Call, err error) {
+	rendered = call
+	rendered.URI, err = vars.render(call.URI)
+	if err != nil {
+		return
+	}
+	rendered.Host, err = vars.render(call.Host)
+	if err != nil {
+		return
+	}
+	if call.Headers != nil {
+		rendered.Headers = make(map[string]string, len(call.Headers))
+		for k, v := range call.Headers {
+			rendered.Headers[k], err = vars.render(v)
+			if err != nil {
+				return
+			}
+		}
+	}
+	rendered.Data, err = vars.renderData(call.Data)
+	return
+}
+
+// extract captures the configured values of a response - the body reader is rewound after each extraction
+func (vars sessionVars) extract(ctx *CheckContext, responseBodyReader io.ReadSeeker) (errs []Error) {
+	for name, extract := range ctx.call.Extract {
+		value, errExtract := extractValue(ctx, extract)
+		responseBodyReader.Seek(0, io.SeekStart)
+		if errExtract != nil {
+			errs = append(errs, Error{
+				Error:    ctx.call.URL + ": could not extract " + name + ": " + errExtract.Error(),
+				Type:     ErrorExtract,
+				Comment:  ctx.call.Comment,
+				Location: ".extract." + name,
+			})
+			continue
+		}
+		vars[name] = value
+	}
+	return
+}
+
+func extractValue(ctx *CheckContext, extract config.Extract) (value string, err error) {
+	switch {
+	case extract.Header != "":
+		if values, ok := ctx.response.Header[http.CanonicalHeaderKey(extract.Header)]; ok && len(values) > 0 {
+			return values[0], nil
+		}
+		return "", errors.New("missing header " + extract.Header)
+	case extract.GoQuery != "":
+		doc, errDoc := goquery.NewDocumentFromReader(ctx.responseBodyReader)
+		if errDoc != nil {
+			return "", errDoc
+		}
+		return check.ExtractGoQuery(doc, extract.GoQuery)
+	}
+	data, errRead := ioutil.ReadAll(ctx.responseBodyReader)
+	if errRead != nil {
+		return "", errors.New("could not read data from response: " + errRead.Error())
+	}
+	if extract.JSONPath != "" {
+		return check.ExtractJSONPath(data, extract.JSONPath)
+	}
+	return check.ExtractRegex(data, extract.Regex)
+}
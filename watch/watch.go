@@ -1,6 +1,7 @@
 package watch
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
@@ -10,10 +11,12 @@ import (
 	"net/http/cookiejar"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"reflect"
 
+	"github.com/foomo/petze/check"
 	"github.com/foomo/petze/config"
 
 	log "github.com/sirupsen/logrus"
@@ -47,15 +50,23 @@ const (
 	ErrorTypeCertificateIsExpiring           = "certificateIsExpiring"
 	ErrorTypeUnexpectedContentType           = "unexpectedContentType"
 	ErrorTypeSessionFail                     = "sessionFail"
+	ErrorTypeTimeout                         = "timeout"
 	ErrorTypeGoQueryMismatch                 = "goqueryMismatch"
 	ErrorTypeGoQuery                         = "goQueryGeneralError"
 	ErrorTypeDataMismatch                    = "dataMismatch"
 	ErrorJsonPath                            = "jsonPathError"
+	ErrorXPath                               = "xPathError"
+	ErrorJSONSchema                          = "jsonSchemaViolation"
+	ErrorOpenAPI                             = "openAPIViolation"
+	ErrorExtract                             = "extractError"
 	ErrorRegex                               = "regexError"
 	ErrorBadResponseBody                     = "badResponseBody"
 	ErrorTypeHeaderMismatch                  = "headerMismatch"
 	ErrorTypeRedirectMismatch                = "redirectMismatch"
 	ErrorTypeReplyMismatch                   = "replyMismatch"
+	ErrorTypeConfig                          = "configError"
+	ErrorTypeRedirectLoop                    = "redirectLoop"
+	ErrorTypeTooManyRedirects                = "tooManyRedirects"
 )
 
 type Error struct {
@@ -71,6 +82,10 @@ type Result struct {
 	Timeout   bool          `json:"timeout"`
 	Timestamp time.Time     `json:"timestamp"`
 	RunTime   time.Duration `json:"runtime"`
+	// how late the session started, because of concurrency limits or a previous session, that took too long
+	Lateness time.Duration `json:"lateness"`
+	// labels of the service
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 func NewResult(id string) *Result {
@@ -85,6 +100,24 @@ func (r *Result) addError(e error, t ErrorType, comment string) {
 	r.Errors = addError(r.Errors, e, t, comment)
 }
 
+func (r *Result) addTimeout(errTimeout *TimeoutError) {
+	r.Timeout = true
+	r.Errors = append(r.Errors, Error{
+		Error:    errTimeout.Error(),
+		Type:     ErrorTypeTimeout,
+		Location: errTimeout.Location,
+	})
+}
+
+// redact removes interpolated secrets from errors before they are reported
+func (r *Result) redact() {
+	for i, e := range r.Errors {
+		r.Errors[i].Error = config.Redact(e.Error)
+		r.Errors[i].Comment = config.Redact(e.Comment)
+		r.Errors[i].Location = config.Redact(e.Location)
+	}
+}
+
 func addError(errors []Error, err error, t ErrorType, comment string) []Error {
 	return append(errors, Error{
 		Error:   err.Error(),
@@ -94,6 +127,12 @@ func addError(errors []Error, err error, t ErrorType, comment string) []Error {
 }
 
 type dialerErrRecorder struct {
+	// dials of the transport may outlive a request
+	sync.Mutex
+	dialErrors
+}
+
+type dialErrors struct {
 	errors                     []Error
 	unknownErr                 error
 	err                        net.Error
@@ -106,8 +145,16 @@ type dialerErrRecorder struct {
 }
 
 type Watcher struct {
-	active                      bool
-	service                     *config.Service
+	service    *config.Service
+	openAPI    *check.OpenAPIDocument
+	errOpenAPI error
+
+	scheduler Scheduler
+
+	// lifecycle - cancelling the context stops the loop and the running session
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
 
 	// notifications
 	didReceiveMailNotification  bool
@@ -118,18 +165,41 @@ type Watcher struct {
 
 // Watch create a watcher and start watching
 func Watch(service *config.Service, chanResult chan Result) *Watcher {
+	w := NewWatcher(service)
+	w.Start(chanResult)
+	return w
+}
 
+// NewWatcher creates a watcher, that has to be started
+func NewWatcher(service *config.Service) *Watcher {
 	w := &Watcher{
-		active:  true,
-		service: service,
+		service:   service,
+		scheduler: unscheduled{},
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	if service.OpenAPI != nil {
+		w.openAPI, w.errOpenAPI = check.NewOpenAPIDocument(service.OpenAPI.Document, service.OpenAPI.BasePath)
 	}
-	go w.watchLoop(chanResult)
 	return w
 }
 
-// Stop watching - beware this is async
+// SetScheduler lets a scheduler decide when sessions run - call it before Start
+func (w *Watcher) SetScheduler(scheduler Scheduler) {
+	w.scheduler = scheduler
+}
+
+// Start watching - results are sent to chanResult until the watcher is stopped
+func (w *Watcher) Start(chanResult chan Result) {
+	w.done = make(chan struct{})
+	go w.watchLoop(chanResult)
+}
+
+// Stop watching - a running session is cancelled, Stop returns once the watcher will not send any more results
 func (w *Watcher) Stop() {
-	w.active = false
+	w.cancel()
+	if w.done != nil {
+		<-w.done
+	}
 }
 
 func (w *Watcher) LastErrors() []Error {
@@ -139,31 +209,75 @@ func (w *Watcher) LastErrors() []Error {
 	return nil
 }
 
+// SetLastErrors transfers the errors of a previous watcher - call it before Start
 func (w *Watcher) SetLastErrors(errs []Error) {
 	w.lastErrors = errs
 }
 
 func (w *Watcher) watchLoop(chanResult chan Result) {
+	defer close(w.done)
 	httpClient, errRecorder := w.getClientAndDialErrRecorder()
 
-	for w.active {
+	scheduled := time.Now()
+	offset := w.scheduler.Offset(w.service)
+	for {
+		timer := time.NewTimer(time.Until(scheduled))
+		select {
+		case <-timer.C:
+		case <-w.ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		release, errAcquire := w.scheduler.Acquire(w.ctx, w.service)
+		if errAcquire != nil {
+			return
+		}
+		lateness := time.Since(scheduled)
 		r := w.watch(httpClient, errRecorder)
-		if w.active {
+		release()
+		if w.ctx.Err() != nil {
+			// results of cancelled sessions are incomplete
+			return
+		}
+		r.Lateness = lateness
+		r.redact()
 
-			// send notifications
-			w.smsNotify(r)
-			w.mailNotify(r)
-			w.slackNotify(r)
+		w.Notify(r)
 
-			chanResult <- *r
-			time.Sleep(w.service.Interval)
+		select {
+		case chanResult <- *r:
+		case <-w.ctx.Done():
+			return
 		}
+		if offset > 0 {
+			// the first session ran immediately, the offset spreads the following ones within the interval
+			scheduled = scheduled.Add(offset - w.service.Interval)
+			offset = 0
+		}
+		scheduled = nextRun(scheduled, w.service.Interval, time.Now())
 	}
 }
 
+// Notify sends notifications for the errors of r, or that they were resolved
+func (w *Watcher) Notify(r *Result) {
+	w.smsNotify(r)
+	w.mailNotify(r)
+	w.slackNotify(r)
+}
+
+// recorded copies the errors recorded so far
+func (e *dialerErrRecorder) recorded() dialErrors {
+	e.Lock()
+	defer e.Unlock()
+	recorded := e.dialErrors
+	recorded.errors = append([]Error{}, e.errors...)
+	return recorded
+}
+
 func (w *Watcher) getClientAndDialErrRecorder() (client *http.Client, errRecorder *dialerErrRecorder) {
 	errRecorder = &dialerErrRecorder{
-		errors: []Error{},
+		dialErrors: dialErrors{errors: []Error{}},
 	}
 	tlsConfig := &tls.Config{}
 	dialer := &net.Dialer{
@@ -172,6 +286,8 @@ func (w *Watcher) getClientAndDialErrRecorder() (client *http.Client, errRecorde
 	}
 	dialTLS := func(network, address string) (conn net.Conn, err error) {
 		tlsConn, tlsErr := tls.DialWithDialer(dialer, network, address, tlsConfig)
+		errRecorder.Lock()
+		defer errRecorder.Unlock()
 		if tlsErr == nil {
 			//conn = tlsConn.(net.Conn)
 			connectionState := tlsConn.ConnectionState()
@@ -244,6 +360,8 @@ func (w *Watcher) getClientAndDialErrRecorder() (client *http.Client, errRecorde
 	dial := func(network, address string) (conn net.Conn, err error) {
 		conn, err = dialer.Dial(network, address)
 		if err != nil {
+			errRecorder.Lock()
+			defer errRecorder.Unlock()
 			switch reflect.TypeOf(err) {
 			case typeOpErr:
 				opError := reflect.ValueOf(err).Elem().Interface().(net.OpError)
@@ -284,6 +402,11 @@ func (w *Watcher) getClientAndDialErrRecorder() (client *http.Client, errRecorde
 func (w *Watcher) watch(client *http.Client, errRecorder *dialerErrRecorder) (r *Result) {
 
 	r = NewResult(w.service.ID)
+	r.Labels = w.service.Labels
+
+	// the session timeout covers the endpoint check and all calls
+	ctx, cancel := w.sessionContext()
+	defer cancel()
 
 	// parsing, the endpoint
 	request, err := http.NewRequest("GET", w.service.Endpoint, nil)
@@ -302,7 +425,7 @@ func (w *Watcher) watch(client *http.Client, errRecorder *dialerErrRecorder) (r
 				return
 			}
 		}
-		_, lookupErr := net.LookupIP(host)
+		_, lookupErr := net.DefaultResolver.LookupIPAddr(ctx, host)
 		if lookupErr != nil {
 			r.addError(lookupErr, ErrorTypeDNS, "")
 			return
@@ -310,8 +433,11 @@ func (w *Watcher) watch(client *http.Client, errRecorder *dialerErrRecorder) (r
 	}
 
 	// i am explicitly not calling http.Get, because it does 30x handling and i do not want that
-	response, err := client.Do(request)
-	r.Errors = append(r.Errors, errRecorder.errors...)
+	probeCtx, cancelProbe := context.WithTimeout(ctx, defaultTimeout)
+	defer cancelProbe()
+	response, err := client.Do(request.WithContext(probeCtx))
+	recorded := errRecorder.recorded()
+	r.Errors = append(r.Errors, recorded.errors...)
 
 	if response != nil && response.Body != nil {
 		// always close the body
@@ -319,29 +445,33 @@ func (w *Watcher) watch(client *http.Client, errRecorder *dialerErrRecorder) (r
 	}
 
 	if err != nil {
+		if errTimeout := w.timeoutError(ctx, probeCtx, defaultTimeout, w.service.Endpoint, ""); errTimeout != nil {
+			r.addTimeout(errTimeout)
+			return
+		}
 		// sth. went wrong
 		r.addError(err, ErrorTypeClientError, "")
 		var netErr net.Error
 		switch true {
-		case errRecorder.tlsHostnameError != nil:
-			r.addError(errRecorder.tlsHostnameError, ErrorTypeTLSHostNameError, "")
-		case errRecorder.tlsSystemRootsError != nil:
-			r.addError(errRecorder.tlsSystemRootsError, ErrorTypeTLSSystemRootsError, "")
-		case errRecorder.tlsUnknownAuthorityError != nil:
-			r.addError(errRecorder.tlsUnknownAuthorityError, ErrorTypeTLSUnknownAuthority, "")
-		case errRecorder.tlsCertificateInvalidError != nil:
-			r.addError(errRecorder.tlsCertificateInvalidError, ErrorTypeTLSCertificateInvalid, "")
-		case errRecorder.unknownErr != nil:
-			r.addError(errRecorder.unknownErr, ErrorTypeUnknownError, "")
-		case errRecorder.dnsConfigError != nil:
-			netErr = errRecorder.dnsConfigError
-			r.addError(errRecorder.dnsConfigError, ErrorTypeDNSConfig, "")
-		case errRecorder.dnsError != nil:
-			netErr = errRecorder.dnsError
-			r.addError(errRecorder.dnsError, ErrorTypeDNS, "")
-		case errRecorder.err != nil:
-			netErr = errRecorder.err
-			r.addError(errRecorder.err, ErrorTypeUnknownError, "")
+		case recorded.tlsHostnameError != nil:
+			r.addError(recorded.tlsHostnameError, ErrorTypeTLSHostNameError, "")
+		case recorded.tlsSystemRootsError != nil:
+			r.addError(recorded.tlsSystemRootsError, ErrorTypeTLSSystemRootsError, "")
+		case recorded.tlsUnknownAuthorityError != nil:
+			r.addError(recorded.tlsUnknownAuthorityError, ErrorTypeTLSUnknownAuthority, "")
+		case recorded.tlsCertificateInvalidError != nil:
+			r.addError(recorded.tlsCertificateInvalidError, ErrorTypeTLSCertificateInvalid, "")
+		case recorded.unknownErr != nil:
+			r.addError(recorded.unknownErr, ErrorTypeUnknownError, "")
+		case recorded.dnsConfigError != nil:
+			netErr = recorded.dnsConfigError
+			r.addError(recorded.dnsConfigError, ErrorTypeDNSConfig, "")
+		case recorded.dnsError != nil:
+			netErr = recorded.dnsError
+			r.addError(recorded.dnsError, ErrorTypeDNS, "")
+		case recorded.err != nil:
+			netErr = recorded.err
+			r.addError(recorded.err, ErrorTypeUnknownError, "")
 		}
 		if netErr != nil {
 			r.Timeout = netErr.Timeout()
@@ -352,9 +482,14 @@ func (w *Watcher) watch(client *http.Client, errRecorder *dialerErrRecorder) (r
 	// prepare to run the session with cookies
 	cookieJar, _ := cookiejar.New(nil)
 	client.Jar = cookieJar
-	errSession := w.runSession(r, client)
-	if errSession != nil {
-		log.Error("session error", errSession)
+	errSession := w.runSession(ctx, r, client)
+	if errTimeout, ok := errSession.(*TimeoutError); ok {
+		r.addTimeout(errTimeout)
+	} else if errSession != nil {
+		// a stopped watcher cancels its session on purpose
+		if w.ctx.Err() == nil {
+			log.Error("session error", config.Redact(errSession.Error()))
+		}
 		r.addError(errSession, ErrorTypeSessionFail, "")
 	}
 	r.RunTime = time.Since(r.Timestamp)
@@ -0,0 +1,134 @@
+package watch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/foomo/petze/config"
+)
+
+func TestWatcherStop(t *testing.T) {
+	sessionStarted := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hang" {
+			sessionStarted <- struct{}{}
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	chanResult := make(chan Result)
+	w := Watch(&config.Service{
+		ID:       "stop",
+		Endpoint: server.URL,
+		Interval: time.Hour,
+		Session:  []config.Call{{URI: "/hang", Timeout: time.Hour}},
+	}, chanResult)
+
+	<-sessionStarted
+	start := time.Now()
+	w.Stop()
+	if time.Since(start) > time.Second {
+		t.Error("the running session was not cancelled")
+	}
+	select {
+	case r := <-chanResult:
+		t.Error("a stopped watcher must not send results", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// stopping twice is fine
+	w.Stop()
+	NewWatcher(&config.Service{}).Stop()
+}
+
+func TestDrainNotifications(t *testing.T) {
+	q := &notificationQueue{}
+	sent := make(chan struct{})
+	q.send(func() {
+		time.Sleep(20 * time.Millisecond)
+		close(sent)
+	})
+	if err := q.drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-sent:
+	default:
+		t.Error("pending notification was not drained")
+	}
+	q.send(func() {
+		t.Error("notifications must be dropped after draining")
+	})
+
+	q = &notificationQueue{}
+	q.send(func() {
+		time.Sleep(time.Second)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.drain(ctx); err == nil {
+		t.Error("expected an error, when draining times out")
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	scheduled := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		now      time.Time
+		expected time.Time
+	}{
+		{now: scheduled.Add(10 * time.Second), expected: scheduled.Add(time.Minute)},
+		// a session, that took longer than the interval, skips the missed runs
+		{now: scheduled.Add(150 * time.Second), expected: scheduled.Add(3 * time.Minute)},
+	}
+	for _, test := range tests {
+		if next := nextRun(scheduled, time.Minute, test.now); !next.Equal(test.expected) {
+			t.Error("unexpected next run", next, test.expected)
+		}
+	}
+}
+
+type offsetScheduler struct {
+	unscheduled
+	offset time.Duration
+}
+
+func (s offsetScheduler) Offset(service *config.Service) time.Duration {
+	return s.offset
+}
+
+func TestWatcherOffset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	chanResult := make(chan Result)
+	w := NewWatcher(&config.Service{
+		ID:       "offset",
+		Endpoint: server.URL,
+		Interval: time.Hour,
+		Session:  []config.Call{{URI: "/"}},
+	})
+	w.SetScheduler(offsetScheduler{offset: 100 * time.Millisecond})
+	start := time.Now()
+	w.Start(chanResult)
+	defer w.Stop()
+
+	// the first session runs immediately, the second one after the offset
+	for _, expected := range []time.Duration{0, 100 * time.Millisecond} {
+		select {
+		case <-chanResult:
+			if elapsed := time.Since(start); elapsed < expected || elapsed > expected+time.Second {
+				t.Error("unexpected start of a session", elapsed, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no result")
+		}
+	}
+}